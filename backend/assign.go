@@ -0,0 +1,81 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Valor del filtro ?assigned_to= que busca las solicitudes sin asignar. No
+// se puede usar como nombre de técnico.
+const unassignedFilter = "unassigned"
+
+// updateAssigneeHandler asigna una solicitud a un técnico con un cuerpo
+// {"assigned_to": "..."}; null o "" la dejan sin asignar. El cambio y su
+// entrada de auditoría se guardan en la misma transacción.
+func updateAssigneeHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		AssignedTo *string `json:"assigned_to"`
+	}
+	if err := decodeStrict(body, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, decodeErrorResponse(err))
+		return
+	}
+	var assignee string
+	if req.AssignedTo != nil {
+		assignee = strings.TrimSpace(*req.AssignedTo)
+	}
+	if utf8.RuneCountInString(assignee) > maxFieldLength || assignee == unassignedFilter {
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Message: fmt.Sprintf("El campo 'assigned_to' debe tener como mucho %d caracteres y no puede ser '%s'", maxFieldLength, unassignedFilter),
+			Code:    codeValidation,
+			Field:   "assigned_to",
+		})
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		var antes sql.NullString
+		err := tx.QueryRowContext(ctx, rebind(`SELECT assigned_to FROM solicitudes WHERE id = ? AND deleted_at IS NULL`), id).Scan(&antes)
+		if err != nil || antes.String == assignee {
+			return err
+		}
+		if err := execOne(ctx, tx, `UPDATE solicitudes SET assigned_to = ? WHERE id = ?`, nullString(assignee), id); err != nil {
+			return err
+		}
+		detail, err := json.Marshal(map[string]string{"antes": antes.String, "despues": assignee})
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, auditAssign, id, adminActor(r), string(detail))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al asignar la solicitud", "id", id)
+		return
+	}
+
+	s, err := findSolicitud(ctx, id)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+
+	responseCache.invalidate()
+	requestLogger(r).Info("Solicitud asignada", "id", id, "assigned_to", assignee)
+	json.NewEncoder(w).Encode(newSolicitudResponse(s))
+}
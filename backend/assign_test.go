@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// listedIDs devuelve los ids del listado de /v1/solicitudes con query.
+func listedIDs(t *testing.T, query string) []int64 {
+	t.Helper()
+	rec := serveAPI(t, "GET", "/v1/solicitudes"+query, "")
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, item := range resp.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestAssignedToFilter(t *testing.T) {
+	openTestDB(t)
+	luis := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	libre := seedSolicitud(t, Solicitud{Nombre: "Luis", Telefono: "8095552222", Servicio: "Mantenimiento de PC"})
+
+	rec := serveAPI(t, "PATCH", fmt.Sprintf("/v1/solicitudes/%d/assignee", luis), `{"assigned_to": " luis "}`)
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		AssignedTo string `json:"assigned_to"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.AssignedTo != "luis" {
+		t.Errorf("assigned_to = %q, want luis", resp.AssignedTo)
+	}
+
+	if got := listedIDs(t, "?assigned_to=unassigned"); len(got) != 1 || got[0] != libre {
+		t.Errorf("sin asignar = %v, want [%d]", got, libre)
+	}
+	if got := listedIDs(t, "?assigned_to=luis"); len(got) != 1 || got[0] != luis {
+		t.Errorf("de luis = %v, want [%d]", got, luis)
+	}
+
+	// null la deja otra vez sin asignar
+	wantStatus(t, serveAPI(t, "PATCH", fmt.Sprintf("/v1/solicitudes/%d/assignee", luis), `{"assigned_to": null}`), http.StatusOK)
+	if got := listedIDs(t, "?assigned_to=unassigned"); len(got) != 2 {
+		t.Errorf("sin asignar = %v, want las dos", got)
+	}
+}
+
+func TestUpdateAssigneeErrors(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	path := fmt.Sprintf("/v1/solicitudes/%d/assignee", id)
+
+	wantStatus(t, serveAPI(t, "PATCH", path, `{"assigned_to": "unassigned"}`), http.StatusBadRequest)
+	wantStatus(t, serveAPI(t, "PATCH", path, `{"asignado": "luis"}`), http.StatusBadRequest)
+	wantStatus(t, serveAPI(t, "PATCH", "/v1/solicitudes/999/assignee", `{"assigned_to": "luis"}`), http.StatusNotFound)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+)
+
+// Acciones que se registran en audit_log.
+const (
+	auditUpdate  = "solicitud.update"
+	auditDelete  = "solicitud.delete"
+	auditRestore = "solicitud.restore"
+	auditStatus  = "solicitud.status"
+	auditAssign  = "solicitud.assign"
+	// Importación con POST /solicitudes/bulk; target_id es 0 porque afecta
+	// a varias solicitudes
+	auditImport = "solicitud.import"
+	// Borrado definitivo con DELETE /solicitudes/by-phone?hard=true
+	auditPurge = "solicitud.purge"
+)
+
+// auditEntry es una fila de audit_log.
+type auditEntry struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`
+	TargetID  int64  `json:"target_id"`
+	Actor     string `json:"actor"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+// listadoAudit es la respuesta paginada de GET /audit.
+type listadoAudit struct {
+	Items  []auditEntry `json:"items"`
+	Limit  int          `json:"limit"`
+	Offset int          `json:"offset"`
+	Total  int          `json:"total"`
+}
+
+// insertAudit registra una acción dentro de la transacción del cambio, para
+// que no pueda haber cambios sin su entrada ni entradas sin cambio.
+func insertAudit(ctx context.Context, tx *sql.Tx, action string, targetID int64, actor, detail string) error {
+	_, err := tx.ExecContext(ctx, rebind(`INSERT INTO audit_log (action, target_id, actor, detail) VALUES (?, ?, ?, ?)`),
+		action, targetID, actor, detail)
+	return err
+}
+
+// auditHandler devuelve las entradas más recientes de audit_log, paginadas
+// con ?limit= y ?offset= como GET /solicitudes.
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, offset, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	listado := listadoAudit{Items: []auditEntry{}, Limit: limit, Offset: offset}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_log`).Scan(&listado.Total); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la auditoría")
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT id, action, target_id, actor, detail, created_at FROM audit_log
+		ORDER BY id DESC LIMIT ? OFFSET ?`), limit, offset)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la auditoría")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e auditEntry
+		var detail sql.NullString
+		var createdAt dbTimestamp
+		if err := rows.Scan(&e.ID, &e.Action, &e.TargetID, &e.Actor, &detail, &createdAt); err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al consultar la auditoría")
+			return
+		}
+		e.Detail = detail.String
+		e.CreatedAt = createdAt.String()
+		listado.Items = append(listado.Items, e)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la auditoría")
+		return
+	}
+
+	json.NewEncoder(w).Encode(listado)
+}
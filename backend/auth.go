@@ -0,0 +1,63 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// adminAPIKey protege los endpoints de lectura/administración
+// (ADMIN_API_KEY). Si está vacía no se exige autenticación, para no
+// complicar el desarrollo local.
+var adminAPIKey string
+
+// requireAdmin exige la clave de administración en la cabecera
+// "Authorization: Bearer <clave>" o "X-API-Key". Responde 401 si falta y
+// 403 si no coincide.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminAPIKey == "" {
+			next(w, r)
+			return
+		}
+
+		key := adminKeyFromRequest(r)
+		if key == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "Se requiere autenticación")
+			return
+		}
+		// Comparación en tiempo constante para no filtrar la clave por tiempos
+		if subtle.ConstantTimeCompare([]byte(key), []byte(adminAPIKey)) != 1 {
+			requestLogger(r).Warn("Clave de administración incorrecta", "ip", clientIP(r), "status", http.StatusForbidden)
+			writeError(w, http.StatusForbidden, codeForbidden, "Clave de acceso incorrecta")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// adminActor identifica al administrador de una petición para la
+// auditoría, sin guardar la clave: "admin:" y el principio de su SHA-256, o
+// "anonymous" si no se exige clave.
+func adminActor(r *http.Request) string {
+	key := adminKeyFromRequest(r)
+	if adminAPIKey == "" || key == "" {
+		return "anonymous"
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "admin:" + hex.EncodeToString(sum[:4])
+}
+
+// adminKeyFromRequest obtiene la clave enviada por el cliente, o "".
+func adminKeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if scheme, token, ok := strings.Cut(auth, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Valores por defecto del corte de lecturas (DB_READ_BREAKER_*)
+const (
+	defaultReadBreakerThreshold = 5
+	defaultReadBreakerCooldown  = 30 * time.Second
+)
+
+// dbBreakerConfig configura el corte de lecturas: tras Threshold fallos
+// seguidos se responde 503 sin consultar la base de datos durante
+// Cooldown. Threshold 0 lo desactiva.
+type dbBreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// circuitBreaker deja de enviar peticiones a la base de datos cuando falla
+// seguido, para responder enseguida en lugar de esperar a cada timeout.
+// Pasado el cooldown deja pasar una petición de prueba: si va bien se
+// vuelve a la normalidad y si no se corta otro cooldown.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// readBreaker protege las rutas de lectura de administración; las
+// escrituras y /submit-service no pasan por él. Config.apply lo crea.
+var readBreaker = newCircuitBreaker(dbBreakerConfig{Threshold: defaultReadBreakerThreshold, Cooldown: defaultReadBreakerCooldown})
+
+func newCircuitBreaker(cfg dbBreakerConfig) *circuitBreaker {
+	return &circuitBreaker{threshold: cfg.Threshold, cooldown: cfg.Cooldown}
+}
+
+// allow indica si la petición puede consultar la base de datos y, si no,
+// cuánto falta para volver a intentarlo.
+func (b *circuitBreaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true, 0
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return false, wait
+	}
+	// Ya hay una petición de prueba en curso
+	if b.probing {
+		return false, time.Second
+	}
+	b.probing = true
+	return true, 0
+}
+
+// record anota el resultado de una petición que allow dejó pasar.
+func (b *circuitBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	probe := b.probing
+	b.probing = false
+	if ok {
+		if b.failures >= b.threshold {
+			logger.Info("Lecturas de la base de datos restablecidas")
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures == b.threshold || (probe && b.failures > b.threshold) {
+		b.openUntil = time.Now().Add(b.cooldown)
+		logger.Warn("Lecturas de la base de datos cortadas tras fallos seguidos", "failures", b.failures, "cooldown", b.cooldown.String())
+	}
+}
+
+// middleware aplica el corte a las peticiones GET y HEAD. Cuenta como fallo
+// cualquier respuesta 5xx, que en estas rutas viene de la base de datos.
+func (b *circuitBreaker) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if b.threshold == 0 || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ok, retryAfter := b.allow()
+		if !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "Servicio temporalmente no disponible")
+			return
+		}
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		b.record(rec.status < http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// serveBreaker pasa una petición por el corte; next responde con *status y
+// cuenta las veces que se le llama.
+func serveBreaker(b *circuitBreaker, method string, status *int, calls *int) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	b.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.WriteHeader(*status)
+	})).ServeHTTP(rec, httptest.NewRequest(method, "/v1/solicitudes", nil))
+	return rec
+}
+
+func TestCircuitBreakerOpensAfterThreshold(t *testing.T) {
+	b := newCircuitBreaker(dbBreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	status, calls := http.StatusInternalServerError, 0
+
+	for range 2 {
+		if rec := serveBreaker(b, "GET", &status, &calls); rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500 del handler", rec.Code)
+		}
+	}
+	rec := serveBreaker(b, "GET", &status, &calls)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("con el corte abierto: status = %d, want 503", rec.Code)
+	}
+	if calls != 2 {
+		t.Errorf("handler llamado %d veces, want 2", calls)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codeServiceUnavailable || apiErr.Message != "Servicio temporalmente no disponible" {
+		t.Errorf("error = %+v", apiErr)
+	}
+
+	// Las escrituras no pasan por el corte
+	status = http.StatusOK
+	if rec := serveBreaker(b, "DELETE", &status, &calls); rec.Code != http.StatusOK || calls != 3 {
+		t.Errorf("DELETE con el corte abierto: status = %d, handler llamado %d veces", rec.Code, calls)
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	b := newCircuitBreaker(dbBreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	calls := 0
+	for _, status := range []int{500, 200, 500, 404, 503} {
+		if rec := serveBreaker(b, "GET", &status, &calls); rec.Code != status {
+			t.Fatalf("status = %d, want %d: los fallos no seguidos no deben cortar", rec.Code, status)
+		}
+	}
+}
+
+func TestCircuitBreakerProbesAfterCooldown(t *testing.T) {
+	b := newCircuitBreaker(dbBreakerConfig{Threshold: 1, Cooldown: 20 * time.Millisecond})
+	status, calls := http.StatusInternalServerError, 0
+
+	serveBreaker(b, "GET", &status, &calls)
+	if ok, _ := b.allow(); ok {
+		t.Fatal("el corte no se abrió")
+	}
+
+	// La prueba falla: otro cooldown
+	time.Sleep(30 * time.Millisecond)
+	if rec := serveBreaker(b, "GET", &status, &calls); rec.Code != http.StatusInternalServerError || calls != 2 {
+		t.Fatalf("prueba: status = %d, handler llamado %d veces", rec.Code, calls)
+	}
+	if rec := serveBreaker(b, "GET", &status, &calls); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("tras fallar la prueba: status = %d, want 503", rec.Code)
+	}
+
+	// La prueba va bien: se cierra
+	time.Sleep(30 * time.Millisecond)
+	status = http.StatusOK
+	for range 3 {
+		if rec := serveBreaker(b, "GET", &status, &calls); rec.Code != http.StatusOK {
+			t.Fatalf("tras la prueba correcta: status = %d, want 200", rec.Code)
+		}
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	b := newCircuitBreaker(dbBreakerConfig{Threshold: 1, Cooldown: time.Millisecond})
+	b.record(false)
+	time.Sleep(5 * time.Millisecond)
+
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("no se dejó pasar la prueba")
+	}
+	// Mientras la prueba no termina, las demás esperan
+	if ok, wait := b.allow(); ok || wait != time.Second {
+		t.Errorf("segunda petición durante la prueba: allow = %v, %s", ok, wait)
+	}
+	b.record(true)
+	if ok, _ := b.allow(); !ok {
+		t.Error("el corte sigue abierto tras una prueba correcta")
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(dbBreakerConfig{Threshold: 0, Cooldown: time.Minute})
+	status, calls := http.StatusInternalServerError, 0
+	for range 5 {
+		serveBreaker(b, "GET", &status, &calls)
+	}
+	if calls != 5 {
+		t.Errorf("handler llamado %d veces, want 5", calls)
+	}
+}
+
+// Con la base de datos caída, las lecturas de administración dejan de
+// esperar al timeout de cada consulta.
+func TestReadBreakerWithDatabaseDown(t *testing.T) {
+	useUnreachableDB(t)
+	prev := readBreaker
+	readBreaker = newCircuitBreaker(dbBreakerConfig{Threshold: 2, Cooldown: time.Minute})
+	t.Cleanup(func() { readBreaker = prev })
+
+	for range 2 {
+		rec := serveAPI(t, "GET", "/v1/solicitudes", "")
+		if rec.Code < http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 5xx con la base de datos caída", rec.Code)
+		}
+	}
+	start := time.Now()
+	rec := serveAPI(t, "GET", "/v1/solicitudes/1", "")
+	wantStatus(t, rec, http.StatusServiceUnavailable)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("el corte tardó %s en responder", elapsed)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Message != "Servicio temporalmente no disponible" {
+		t.Errorf("message = %q", apiErr.Message)
+	}
+}
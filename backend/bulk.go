@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Máximo de solicitudes por petición de POST /solicitudes/bulk.
+const maxBulkImport = 1000
+
+// Filas por cada INSERT de la importación, para no acercarse al límite de
+// parámetros por sentencia de los drivers.
+const bulkInsertBatch = 100
+
+// bulkImportError describe una solicitud del array que no se importó.
+type bulkImportError struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"`
+}
+
+// bulkImportResult es la respuesta de POST /solicitudes/bulk.
+type bulkImportResult struct {
+	Inserted int               `json:"inserted"`
+	Errors   []bulkImportError `json:"errors"`
+}
+
+// bulkImportHandler importa un array JSON de solicitudes. Las que no son
+// válidas se saltan y se informan en errors; las demás se guardan en una
+// sola transacción, así que un error de la base de datos no deja ninguna.
+func bulkImportHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var items []json.RawMessage
+	if err := decodeStrict(body, &items); err != nil {
+		apiErr := decodeErrorResponse(err)
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			apiErr.Message = "El cuerpo debe ser un array JSON de solicitudes"
+		}
+		writeAPIError(w, http.StatusBadRequest, apiErr)
+		return
+	}
+	if len(items) > maxBulkImport {
+		writeError(w, http.StatusBadRequest, codeValidation, fmt.Sprintf("No se pueden importar más de %d solicitudes a la vez", maxBulkImport))
+		return
+	}
+
+	result := bulkImportResult{Errors: []bulkImportError{}}
+	valid := make([]pendingSolicitud, 0, len(items))
+	for i, raw := range items {
+		var s Solicitud
+		if err := decodeStrict(raw, &s); err != nil {
+			apiErr := decodeErrorResponse(err)
+			if apiErr.Field == "" {
+				apiErr.Message = "Cada elemento debe ser un objeto JSON con nombre, telefono y servicio"
+			}
+			result.Errors = append(result.Errors, bulkImportError{Index: i, Message: apiErr.Message, Field: apiErr.Field})
+			continue
+		}
+		s, err := prepareSolicitud(requestLogger(r), s)
+		if err != nil {
+			// Una entrada por cada campo inválido del elemento
+			var vErrs validationErrors
+			if !errors.As(err, &vErrs) {
+				vErrs = validationErrors{{Message: err.Error()}}
+			}
+			for _, vErr := range vErrs {
+				result.Errors = append(result.Errors, bulkImportError{Index: i, Message: vErr.Message, Field: vErr.Field})
+			}
+			continue
+		}
+		valid = append(valid, pendingSolicitud{Solicitud: s})
+	}
+
+	if len(valid) > 0 {
+		ctx, cancel := dbContext(r)
+		defer cancel()
+
+		err := withTx(ctx, func(tx *sql.Tx) error {
+			for start := 0; start < len(valid); start += bulkInsertBatch {
+				if err := insertSolicitudes(ctx, tx, valid[start:min(start+bulkInsertBatch, len(valid))]); err != nil {
+					return err
+				}
+			}
+			detail, _ := json.Marshal(map[string]int{"inserted": len(valid), "skipped": len(items) - len(valid)})
+			return insertAudit(ctx, tx, auditImport, 0, adminActor(r), string(detail))
+		})
+		if err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al importar las solicitudes", "count", len(valid))
+			return
+		}
+		responseCache.invalidate()
+	}
+
+	result.Inserted = len(valid)
+	requestLogger(r).Info("Importación de solicitudes completada", "inserted", result.Inserted, "errors", len(result.Errors))
+	lang := responseLang(w)
+	for i := range result.Errors {
+		result.Errors[i].Message = translate(lang, result.Errors[i].Message)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// insertSolicitudes guarda varias solicitudes con un único INSERT de varias
+// filas.
+func insertSolicitudes(ctx context.Context, q querier, batch []pendingSolicitud) error {
+	rows := make([]string, len(batch))
+	args := make([]any, 0, 8*len(batch))
+	for i, p := range batch {
+		rows[i] = "(?, ?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, p.Nombre, p.Telefono, p.Servicio, nullString(p.Email), nullString(p.Mensaje), nullString(p.HorarioPreferido), nullString(p.Client.IP), nullString(p.Client.UserAgent))
+	}
+	_, err := q.ExecContext(ctx, rebind(`INSERT INTO solicitudes (nombre, telefono, servicio, email, mensaje, horario_preferido, ip_address, user_agent) VALUES `+strings.Join(rows, ", ")), args...)
+	return err
+}
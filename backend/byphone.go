@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// phoneCandidates devuelve los valores de telefono que corresponden a raw
+// tal como los guarda prepareSolicitud: el número normalizado y, para las
+// filas que no se pudieron normalizar o son anteriores a la normalización,
+// el texto recortado. Devuelve error si falta el parámetro.
+func phoneCandidates(raw string) ([]any, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, errMissingTelefono
+	}
+	candidates := []any{raw}
+	if normalized, err := normalizePhone(raw, defaultCountryCode); err == nil && normalized != raw {
+		candidates = append(candidates, normalized)
+	}
+	return candidates, nil
+}
+
+// errMissingTelefono es el error de ?telefono= vacío en /solicitudes/by-phone.
+var errMissingTelefono = errors.New("El parámetro 'telefono' es obligatorio")
+
+// inPlaceholders devuelve "?, ?, ..." con n marcadores para un IN (...).
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// solicitudesByPhoneHandler devuelve todas las solicitudes de un teléfono,
+// eliminadas incluidas, para atender las peticiones de acceso a los datos.
+// El teléfono se normaliza igual que al guardarlo, así que da igual el
+// formato en que se escriba.
+func solicitudesByPhoneHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	candidates, err := phoneCandidates(r.URL.Query().Get("telefono"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes WHERE telefono IN (`+
+		inPlaceholders(len(candidates))+`) ORDER BY id DESC`), candidates...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+	defer rows.Close()
+
+	items := []SolicitudGuardada{}
+	for rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+			return
+		}
+		items = append(items, s)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"items": newSolicitudResponses(items), "total": len(items)})
+}
+
+// deleteSolicitudesByPhoneHandler elimina todas las solicitudes de un
+// teléfono para atender las peticiones de borrado. Por defecto es un
+// borrado lógico como DELETE /solicitudes/{id}; con ?hard=true se borran
+// definitivamente y se vacía el detalle de su auditoría, que guarda los
+// datos anteriores de las ediciones.
+func deleteSolicitudesByPhoneHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	candidates, err := phoneCandidates(query.Get("telefono"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+	hard := false
+	if raw := query.Get("hard"); raw != "" {
+		if hard, err = strconv.ParseBool(raw); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, "El parámetro 'hard' debe ser true o false")
+			return
+		}
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	var ids []int64
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		ids, err = deleteByPhone(ctx, tx, candidates, hard, adminActor(r))
+		return err
+	})
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al eliminar las solicitudes")
+		return
+	}
+
+	if len(ids) > 0 {
+		responseCache.invalidate()
+	}
+	requestLogger(r).Info("Solicitudes eliminadas por teléfono", "deleted", len(ids), "hard", hard, "ids", ids)
+	json.NewEncoder(w).Encode(map[string]any{"deleted": len(ids), "hard": hard})
+}
+
+// deleteByPhone borra dentro de tx las solicitudes cuyo teléfono está en
+// candidates y anota cada una en la auditoría. Devuelve los ids afectados.
+func deleteByPhone(ctx context.Context, tx *sql.Tx, candidates []any, hard bool, actor string) ([]int64, error) {
+	where := `telefono IN (` + inPlaceholders(len(candidates)) + `)`
+	if !hard {
+		where += ` AND deleted_at IS NULL`
+	}
+	rows, err := tx.QueryContext(ctx, rebind(`SELECT id FROM solicitudes WHERE `+where), candidates...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	idArgs := make([]any, len(ids))
+	for i, id := range ids {
+		idArgs[i] = id
+	}
+	placeholders := inPlaceholders(len(ids))
+	action := auditDelete
+	if hard {
+		action = auditPurge
+		if _, err := tx.ExecContext(ctx, rebind(`UPDATE audit_log SET detail = '' WHERE target_id IN (`+placeholders+`)`), idArgs...); err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, rebind(`DELETE FROM solicitudes WHERE id IN (`+placeholders+`)`), idArgs...)
+	} else {
+		_, err = tx.ExecContext(ctx, rebind(`UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id IN (`+placeholders+`)`), idArgs...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if err := insertAudit(ctx, tx, action, id, actor, ""); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+// withCountryCode fija defaultCountryCode mientras dura el test.
+func withCountryCode(t *testing.T, code string) {
+	t.Helper()
+	prev := defaultCountryCode
+	defaultCountryCode = code
+	t.Cleanup(func() { defaultCountryCode = prev })
+}
+
+// byPhoneIDs devuelve los ids que lista GET /solicitudes/by-phone.
+func byPhoneIDs(t *testing.T, telefono string) []int64 {
+	t.Helper()
+	rec := serveAPI(t, "GET", "/v1/solicitudes/by-phone?telefono="+url.QueryEscape(telefono), "")
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Items []struct {
+			ID int64 `json:"id"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	ids := []int64{}
+	for _, item := range resp.Items {
+		ids = append(ids, item.ID)
+	}
+	if resp.Total != len(ids) {
+		t.Errorf("total = %d con %d solicitudes", resp.Total, len(ids))
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// seedByPhone guarda una solicitud de Ana por el formulario, que normaliza
+// el teléfono, y otra antigua con el teléfono tal cual se escribió.
+func seedByPhone(t *testing.T) (submitted, legacy, other int64) {
+	t.Helper()
+	rec := submit(t, solicitudBody("Ana", "(809) 555-7711", "Mantenimiento de PC"))
+	wantStatus(t, rec, http.StatusCreated)
+	submitted = responseID(t, rec)
+	legacy = seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "809-555-7711", Servicio: "Recuperación de Datos"})
+	other = seedSolicitud(t, Solicitud{Nombre: "Luis", Telefono: "+18095552222", Servicio: "Mantenimiento de PC"})
+	return submitted, legacy, other
+}
+
+func TestSolicitudesByPhoneMatchesAnyFormat(t *testing.T) {
+	openTestDB(t)
+	withCountryCode(t, "1")
+	submitted, legacy, _ := seedByPhone(t)
+
+	for _, telefono := range []string{"(809) 555-7711", "+1 809 555 7711", "+18095557711", "001 809-555-7711", "8095557711"} {
+		if got := byPhoneIDs(t, telefono); !slices.Equal(got, []int64{submitted}) {
+			t.Errorf("%q: ids = %v, want [%d]", telefono, got, submitted)
+		}
+	}
+	// Escrita igual que la fila antigua también la encuentra
+	if got := byPhoneIDs(t, " 809-555-7711 "); !slices.Equal(got, []int64{submitted, legacy}) {
+		t.Errorf("formato de la fila antigua: ids = %v, want [%d %d]", got, submitted, legacy)
+	}
+	if got := byPhoneIDs(t, "+34 600 000 000"); len(got) != 0 {
+		t.Errorf("otro teléfono: ids = %v", got)
+	}
+}
+
+func TestSolicitudesByPhoneIncludesDeleted(t *testing.T) {
+	openTestDB(t)
+	withCountryCode(t, "1")
+	submitted, _, _ := seedByPhone(t)
+	db.Exec(`UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, submitted)
+
+	if got := byPhoneIDs(t, "+18095557711"); !slices.Equal(got, []int64{submitted}) {
+		t.Errorf("ids = %v, want [%d] aunque esté eliminada", got, submitted)
+	}
+}
+
+func TestDeleteSolicitudesByPhone(t *testing.T) {
+	openTestDB(t)
+	withCountryCode(t, "1")
+	submitted, legacy, other := seedByPhone(t)
+
+	rec := serveAPI(t, "DELETE", "/v1/solicitudes/by-phone?telefono="+url.QueryEscape("809-555-7711"), "")
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Deleted int  `json:"deleted"`
+		Hard    bool `json:"hard"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Deleted != 2 || resp.Hard {
+		t.Errorf("respuesta = %s, want 2 eliminadas sin hard", rec.Body)
+	}
+
+	// Borrado lógico: siguen en la tabla, con deleted_at y en la auditoría
+	var deleted, audited int
+	db.QueryRow(`SELECT COUNT(*) FROM solicitudes WHERE deleted_at IS NOT NULL`).Scan(&deleted)
+	db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action = ? AND target_id IN (?, ?)`, auditDelete, submitted, legacy).Scan(&audited)
+	if deleted != 2 || audited != 2 {
+		t.Errorf("eliminadas = %d y auditadas = %d, want 2 y 2", deleted, audited)
+	}
+	if n := countSolicitudes(t); n != 3 {
+		t.Errorf("solicitudes = %d, want 3", n)
+	}
+
+	// Repetirlo no vuelve a eliminar las ya eliminadas
+	rec = serveAPI(t, "DELETE", "/v1/solicitudes/by-phone?telefono=%2B18095557711", "")
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.Deleted != 0 {
+		t.Errorf("segunda vez: deleted = %d, want 0", resp.Deleted)
+	}
+	if got := listedIDs(t, ""); !slices.Equal(got, []int64{other}) {
+		t.Errorf("listado = %v, want [%d]", got, other)
+	}
+}
+
+func TestHardDeleteSolicitudesByPhone(t *testing.T) {
+	openTestDB(t)
+	withCountryCode(t, "1")
+	submitted, legacy, other := seedByPhone(t)
+	// Una edición anterior dejó los datos viejos en la auditoría
+	db.Exec(`INSERT INTO audit_log (action, target_id, actor, detail) VALUES (?, ?, ?, ?)`, auditUpdate, submitted, "admin", `{"nombre": "Ana María"}`)
+	db.Exec(`UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, legacy)
+
+	rec := serveAPI(t, "DELETE", "/v1/solicitudes/by-phone?hard=true&telefono="+url.QueryEscape("809-555-7711"), "")
+	wantStatus(t, rec, http.StatusOK)
+
+	// Las eliminadas también se borran definitivamente
+	if got := remainingIDs(t); !slices.Equal(got, []int64{other}) {
+		t.Errorf("quedan %v, want [%d]", got, other)
+	}
+	var withDetail, purged int
+	db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE target_id IN (?, ?) AND detail <> ''`, submitted, legacy).Scan(&withDetail)
+	db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE action = ?`, auditPurge).Scan(&purged)
+	if withDetail != 0 || purged != 2 {
+		t.Errorf("auditoría con detalle = %d y purgas = %d, want 0 y 2", withDetail, purged)
+	}
+}
+
+func TestByPhoneErrors(t *testing.T) {
+	openTestDB(t)
+	for _, tc := range []struct {
+		method, query, message string
+	}{
+		{"GET", "", "El parámetro 'telefono' es obligatorio"},
+		{"GET", "?telefono=%20%20", "El parámetro 'telefono' es obligatorio"},
+		{"DELETE", "", "El parámetro 'telefono' es obligatorio"},
+		{"DELETE", "?telefono=8095557711&hard=si", "El parámetro 'hard' debe ser true o false"},
+	} {
+		rec := serveAPI(t, tc.method, "/v1/solicitudes/by-phone"+tc.query, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s %s: status = %d, want 400", tc.method, tc.query, rec.Code)
+			continue
+		}
+		var apiErr APIError
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if apiErr.Code != codeInvalidParameter || apiErr.Message != tc.message {
+			t.Errorf("%s %s: error = %+v, want %s", tc.method, tc.query, apiErr, tc.message)
+		}
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("solicitudes = %d", n)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Tiempo por defecto que se guardan las respuestas cacheadas (CACHE_TTL)
+const defaultCacheTTL = 30 * time.Second
+
+// responseCache guarda respuestas que cambian poco (/services y
+// /stats/by-service). Se vacía cada vez que se modifica una solicitud.
+var responseCache = newTTLCache(defaultCacheTTL)
+
+// ttlCache es una caché en memoria en la que cada entrada caduca ttl
+// después de guardarse. Con ttl <= 0 no guarda nada.
+type ttlCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+func newTTLCache(ttl time.Duration) *ttlCache {
+	return &ttlCache{ttl: ttl, now: time.Now, entries: make(map[string]cacheEntry)}
+}
+
+// get devuelve el valor guardado en key si no ha caducado.
+func (c *ttlCache) get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(e.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *ttlCache) set(key string, value any) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cacheEntry{value: value, expires: c.now().Add(c.ttl)}
+}
+
+// invalidate vacía la caché.
+func (c *ttlCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTTLCacheExpires(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	c := newTTLCache(time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.set("k", 1)
+	now = now.Add(59 * time.Second)
+	if v, ok := c.get("k"); !ok || v != 1 {
+		t.Errorf("dentro del TTL: get = %v, %v", v, ok)
+	}
+	now = now.Add(time.Second)
+	if _, ok := c.get("k"); ok {
+		t.Error("la entrada no caducó")
+	}
+}
+
+func TestTTLCacheInvalidateAndDisabled(t *testing.T) {
+	c := newTTLCache(time.Minute)
+	c.set("a", 1)
+	c.set("b", 2)
+	c.invalidate()
+	if _, ok := c.get("a"); ok {
+		t.Error("invalidate no vació la caché")
+	}
+
+	disabled := newTTLCache(0)
+	disabled.set("a", 1)
+	if _, ok := disabled.get("a"); ok {
+		t.Error("con TTL 0 no se debe guardar nada")
+	}
+}
+
+func TestStatsByServiceCachedWithinTTL(t *testing.T) {
+	openTestDB(t)
+	seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	first := httptest.NewRecorder()
+	statsByServiceHandler(first, httptest.NewRequest("GET", "/v1/stats/by-service", nil))
+	wantStatus(t, first, http.StatusOK)
+
+	// Con la base de datos caída, la segunda llamada solo puede salir de la
+	// caché
+	useUnreachableDB(t)
+	second := httptest.NewRecorder()
+	statsByServiceHandler(second, httptest.NewRequest("GET", "/v1/stats/by-service", nil))
+	wantStatus(t, second, http.StatusOK)
+	if second.Body.String() != first.Body.String() {
+		t.Errorf("body = %s, want %s", second.Body, first.Body)
+	}
+
+	// Otra consulta no comparte la entrada
+	other := httptest.NewRecorder()
+	statsByServiceHandler(other, httptest.NewRequest("GET", "/v1/stats/by-service?servicio=Mantenimiento+de+PC", nil))
+	if other.Code < http.StatusInternalServerError {
+		t.Error("un filtro distinto salió de la caché")
+	}
+
+	// Tras una escritura se vuelve a consultar
+	responseCache.invalidate()
+	third := httptest.NewRecorder()
+	statsByServiceHandler(third, httptest.NewRequest("GET", "/v1/stats/by-service", nil))
+	if third.Code < http.StatusInternalServerError {
+		t.Error("la caché no se vació")
+	}
+}
+
+func TestServicesCached(t *testing.T) {
+	responseCache.invalidate()
+	t.Cleanup(responseCache.invalidate)
+	withDailyQuotas(t, []string{"Redes"}, nil)
+
+	first := httptest.NewRecorder()
+	servicesHandler(first, httptest.NewRequest("GET", "/v1/services", nil))
+	allowedServices = []string{"Otro"}
+	second := httptest.NewRecorder()
+	servicesHandler(second, httptest.NewRequest("GET", "/v1/services", nil))
+	if got := second.Body.String(); got != "[\"Redes\"]\n" {
+		t.Errorf("body = %q, want la lista cacheada", got)
+	}
+}
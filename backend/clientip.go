@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Proxies de confianza por defecto (TRUSTED_PROXIES): las redes privadas y
+// de loopback, desde las que llega el proxy de Railway.
+var defaultTrustedProxies = []string{
+	"127.0.0.0/8", "::1/128",
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10",
+	"fc00::/7",
+}
+
+// trustedProxies son los rangos cuyos X-Forwarded-For se creen. Una
+// petición que llega de cualquier otra dirección puede haber escrito la
+// cabecera ella misma.
+var trustedProxies = mustParseProxies(defaultTrustedProxies)
+
+// parseTrustedProxies convierte una lista de CIDR o IPs sueltas en rangos.
+func parseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, v := range values {
+		if !strings.Contains(v, "/") {
+			addr, err := netip.ParseAddr(v)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES contiene un rango no válido: %q", v)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(v)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES contiene un rango no válido: %q", v)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func mustParseProxies(values []string) []netip.Prefix {
+	prefixes, err := parseTrustedProxies(values)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// isTrustedProxy indica si ip pertenece a alguno de los trustedProxies.
+func isTrustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP obtiene la IP del cliente. X-Forwarded-For solo se tiene en
+// cuenta si la conexión viene de un proxy de confianza, y entonces se
+// recorre de derecha a izquierda saltando los proxies de confianza: la
+// primera IP que no lo es es la del cliente. Las de más a la izquierda las
+// puede inventar el propio cliente.
+func clientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !isTrustedProxy(remote) {
+		return remote
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		if _, err := netip.ParseAddr(hops[i]); err != nil {
+			// Una entrada inválida no es de un proxy nuestro: lo anterior
+			// no es fiable
+			break
+		}
+		if !isTrustedProxy(hops[i]) || i == 0 {
+			return hops[i]
+		}
+	}
+	return remote
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	prev := trustedProxies
+	trustedProxies = mustParseProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	t.Cleanup(func() { trustedProxies = prev })
+
+	for _, tc := range []struct {
+		name, remote string
+		forwarded    []string
+		want         string
+	}{
+		{"sin proxy", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"cabecera falsa desde fuera", "203.0.113.7:5000", []string{"1.2.3.4"}, "203.0.113.7"},
+		{"cadena falsa desde fuera", "203.0.113.7:5000", []string{"1.2.3.4, 10.0.0.5"}, "203.0.113.7"},
+		{"proxy de confianza", "10.0.0.2:5000", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"IP suelta de confianza", "192.0.2.1:5000", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"cliente inventa la izquierda", "10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.9"}, "198.51.100.9"},
+		{"varios proxies", "10.0.0.2:5000", []string{"198.51.100.9, 10.0.0.7, 10.0.0.8"}, "198.51.100.9"},
+		{"varias cabeceras", "10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.9"}, "198.51.100.9"},
+		{"solo proxies", "10.0.0.2:5000", []string{"10.0.0.7"}, "10.0.0.7"},
+		{"entrada inválida", "10.0.0.2:5000", []string{"198.51.100.9, basura"}, "10.0.0.2"},
+		{"proxy sin cabecera", "10.0.0.2:5000", nil, "10.0.0.2"},
+		{"IPv6 de fuera", "[2001:db8::1]:5000", []string{"1.2.3.4"}, "2001:db8::1"},
+		{"IPv4 mapeada de confianza", "[::ffff:10.0.0.2]:5000", []string{"198.51.100.9"}, "198.51.100.9"},
+		{"sin puerto", "203.0.113.7", []string{"1.2.3.4"}, "203.0.113.7"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remote
+			for _, v := range tc.forwarded {
+				req.Header.Add("X-Forwarded-For", v)
+			}
+			if got := clientIP(req); got != tc.want {
+				t.Errorf("clientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	prefixes, err := parseTrustedProxies([]string{"10.1.2.3/8", "192.0.2.1", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.0/8", "192.0.2.1/32", "2001:db8::/32"}
+	for i, p := range prefixes {
+		if p.String() != want[i] {
+			t.Errorf("rango %d = %s, want %s", i, p, want[i])
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "proxy.local", "10.0.0/8"} {
+		if _, err := parseTrustedProxies([]string{bad}); err == nil {
+			t.Errorf("parseTrustedProxies(%q) no devolvió error", bad)
+		}
+	}
+}
+
+func TestLoadConfigTrustedProxies(t *testing.T) {
+	setTestEnv(t, map[string]string{"TRUSTED_PROXIES": "10.0.0.0/8,nada"})
+	if _, err := LoadConfig(); err == nil {
+		t.Error("LoadConfig aceptó un TRUSTED_PROXIES no válido")
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Tamaño mínimo por defecto de las respuestas que se comprimen
+// (GZIP_MIN_SIZE): por debajo, gzip cuesta más CPU de lo que ahorra.
+const defaultGzipMinSize = 1024
+
+var gzipMinSize = defaultGzipMinSize
+
+var gzipWriterPool = sync.Pool{
+	New: func() any { return gzip.NewWriter(nil) },
+}
+
+// gzipMiddleware comprime la respuesta con gzip cuando el cliente lo acepta.
+// Las respuestas que ya traen Content-Encoding se envían tal cual.
+func gzipMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == "HEAD" || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip indica si la cabecera Accept-Encoding admite gzip (con q > 0).
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.TrimSpace(name) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter decide si comprimir al recibir los primeros
+// gzipMinSize bytes: no se comprime lo que ya está codificado, las
+// respuestas sin cuerpo ni las que no llegan al mínimo. Hasta decidir, el
+// estado y el cuerpo se guardan en buf.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	buf         []byte
+	status      int
+	wroteHeader bool
+	decided     bool
+	compress    bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.status = status
+
+	h := g.Header()
+	if h.Get("Content-Encoding") != "" || status < http.StatusOK ||
+		status == http.StatusNoContent || status == http.StatusNotModified {
+		g.decide(false)
+		return
+	}
+	// Si el handler ya indica el tamaño no hace falta esperar al cuerpo
+	if length, err := strconv.Atoi(h.Get("Content-Length")); err == nil {
+		g.decide(length >= gzipMinSize)
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		// Detectar el tipo sobre los datos sin comprimir; si no, net/http lo
+		// deduciría de los bytes ya comprimidos.
+		if g.Header().Get("Content-Type") == "" {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+	if !g.decided {
+		g.buf = append(g.buf, b...)
+		if len(g.buf) >= gzipMinSize {
+			if err := g.decide(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(b), nil
+	}
+	if !g.compress {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+// decide envía la cabecera, con o sin compresión, y lo que hubiera en buf.
+func (g *gzipResponseWriter) decide(compress bool) error {
+	g.decided = true
+	g.compress = compress
+	if compress {
+		h := g.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzipWriterPool.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if compress {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush envía al cliente lo comprimido hasta ahora (útil al hacer streaming).
+// Una respuesta que hace flush antes de llegar al mínimo se comprime igual:
+// es de las que van por partes y suelen ser largas.
+func (g *gzipResponseWriter) Flush() {
+	if g.wroteHeader && !g.decided {
+		g.decide(true)
+	}
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close envía sin comprimir lo que no llegó al mínimo y termina el gzip.
+func (g *gzipResponseWriter) close() {
+	if g.wroteHeader && !g.decided {
+		g.decide(false)
+	}
+	if g.gz == nil {
+		return
+	}
+	g.gz.Close()
+	gzipWriterPool.Put(g.gz)
+	g.gz = nil
+}
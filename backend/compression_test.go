@@ -0,0 +1,219 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveGzip pasa una petición con Accept-Encoding por gzipMiddleware.
+func serveGzip(acceptEncoding string, h http.HandlerFunc) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/v1/solicitudes", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	gzipMiddleware(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzipCompressesLargeResponses(t *testing.T) {
+	body := `{"items": [` + strings.Repeat(`{"nombre": "Ana"},`, 200) + `{}]}`
+	rec := serveGzip("gzip, deflate", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	})
+
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Errorf("Vary = %q", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != body {
+		t.Errorf("cuerpo descomprimido distinto del original (%d bytes)", len(decoded))
+	}
+}
+
+func TestGzipDetectsContentTypeBeforeCompressing(t *testing.T) {
+	rec := serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("texto plano ", 200)))
+	})
+	if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain; charset=utf-8", got)
+	}
+}
+
+func TestGzipLeavesResponsesUncompressed(t *testing.T) {
+	large := strings.Repeat("a", gzipMinSize*2)
+	for _, tc := range []struct {
+		name, acceptEncoding string
+		handler              http.HandlerFunc
+		wantBody             string
+	}{
+		{"sin Accept-Encoding", "", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(large)) }, large},
+		{"gzip;q=0", "gzip;q=0", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(large)) }, large},
+		{"pequeña", "gzip", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{"ok": true}`)) }, `{"ok": true}`},
+		{"ya codificada", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(large))
+		}, large},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serveGzip(tc.acceptEncoding, tc.handler)
+			if got := rec.Header().Get("Content-Encoding"); got == "gzip" {
+				t.Errorf("Content-Encoding = gzip")
+			}
+			if rec.Body.String() != tc.wantBody {
+				t.Errorf("cuerpo modificado (%d bytes)", rec.Body.Len())
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"gzip":             true,
+		"GZIP":             true,
+		"deflate, gzip":    true,
+		"gzip;q=0.5":       true,
+		"gzip; q=0":        false,
+		"deflate, br":      false,
+		"":                 false,
+		"x-gzip, identity": false,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("acceptsGzip(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// withGzipMinSize fija gzipMinSize mientras dura el test.
+func withGzipMinSize(t *testing.T, size int) {
+	t.Helper()
+	prev := gzipMinSize
+	gzipMinSize = size
+	t.Cleanup(func() { gzipMinSize = prev })
+}
+
+// gunzip descomprime el cuerpo de rec, o falla si no viene en gzip.
+func gunzip(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(decoded)
+}
+
+func TestGzipMinSizeThreshold(t *testing.T) {
+	withGzipMinSize(t, 100)
+	for _, tc := range []struct {
+		name         string
+		size         int
+		wantCompress bool
+	}{
+		{"por debajo", 99, false},
+		{"justo el mínimo", 100, true},
+		{"por encima", 5000, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			body := strings.Repeat("a", tc.size)
+			rec := serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				// Por partes, para que el mínimo se alcance a mitad de una escritura
+				for i := 0; i < len(body); i += 30 {
+					w.Write([]byte(body[i:min(i+30, len(body))]))
+				}
+			})
+			if rec.Code != http.StatusCreated {
+				t.Errorf("status = %d, want 201", rec.Code)
+			}
+			if !tc.wantCompress {
+				if got := rec.Header().Get("Content-Encoding"); got != "" {
+					t.Errorf("Content-Encoding = %q en una respuesta pequeña", got)
+				}
+				if rec.Body.String() != body {
+					t.Errorf("cuerpo modificado (%d bytes)", rec.Body.Len())
+				}
+				return
+			}
+			if got := gunzip(t, rec); got != body {
+				t.Errorf("cuerpo descomprimido distinto del original (%d bytes)", len(got))
+			}
+		})
+	}
+}
+
+func TestGzipUsesContentLength(t *testing.T) {
+	withGzipMinSize(t, 100)
+	large := strings.Repeat("a", 200)
+	rec := serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "200")
+		w.Write([]byte(large))
+	})
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q en una respuesta comprimida", got)
+	}
+	if got := gunzip(t, rec); got != large {
+		t.Errorf("cuerpo descomprimido distinto del original (%d bytes)", len(got))
+	}
+
+	rec = serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2")
+		w.Write([]byte("ok"))
+	})
+	if rec.Header().Get("Content-Encoding") != "" || rec.Header().Get("Content-Length") != "2" {
+		t.Errorf("respuesta pequeña: Content-Encoding = %q, Content-Length = %q", rec.Header().Get("Content-Encoding"), rec.Header().Get("Content-Length"))
+	}
+}
+
+func TestGzipFlushCompressesBeforeMinSize(t *testing.T) {
+	withGzipMinSize(t, 1000)
+	rec := serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("primera parte\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("segunda parte\n"))
+	})
+	if got := gunzip(t, rec); got != "primera parte\nsegunda parte\n" {
+		t.Errorf("cuerpo = %q", got)
+	}
+}
+
+func TestGzipMinSizeZeroCompressesEverything(t *testing.T) {
+	withGzipMinSize(t, 0)
+	rec := serveGzip("gzip", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	})
+	if got := gunzip(t, rec); got != `{}` {
+		t.Errorf("cuerpo = %q", got)
+	}
+}
+
+func TestLoadConfigGzipMinSize(t *testing.T) {
+	setTestEnv(t, map[string]string{"GZIP_MIN_SIZE": "-1"})
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "GZIP_MIN_SIZE") {
+		t.Errorf("LoadConfig = %v, want error de GZIP_MIN_SIZE", err)
+	}
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joho/godotenv"
+)
+
+// Fichero de variables de entorno para desarrollo local (ENV_FILE)
+const defaultEnvFile = ".env"
+
+// Interfaz en la que escucha el servidor si no se define LISTEN_ADDR
+const defaultListenAddr = "0.0.0.0"
+
+// Config reúne toda la configuración de la aplicación, leída de las
+// variables de entorno por LoadConfig.
+type Config struct {
+	LogLevel slog.Level
+
+	// EnvFile es el fichero .env del que se cargaron variables, o "" si no
+	// existe.
+	EnvFile string
+
+	// Base de datos
+	DBDriver       string
+	DatabaseURL    string
+	DBPool         dbPoolConfig
+	DBRetry        dbRetryConfig
+	DBQueryTimeout time.Duration
+	DBReadBreaker  dbBreakerConfig
+	// MaintenanceMode arranca rechazando las escrituras (MAINTENANCE_MODE)
+	MaintenanceMode bool
+
+	// Validación y deduplicación de solicitudes
+	TelefonoRegexp     *regexp.Regexp
+	ServicioRegexp     *regexp.Regexp
+	DefaultCountryCode string
+	AllowedServices    []string
+	HoneypotField      string
+	MaxBodyBytes       int64
+	MaxMensajeLength   int
+	StripHTML          bool
+	DedupWindow        time.Duration
+	DedupStrategy      string
+	// DailyQuotas limita las solicitudes al día de algunos servicios
+	// (SERVICE_DAILY_QUOTAS)
+	DailyQuotas    map[string]int
+	IdempotencyTTL time.Duration
+
+	// RecaptchaSecret activa la verificación de reCAPTCHA v3 en
+	// /submit-service
+	RecaptchaSecret   string
+	RecaptchaMinScore float64
+
+	// Límite de peticiones por IP para /submit-service
+	RateLimitPerMinute float64
+	RateLimitBurst     int
+
+	// WriteBuffer guarda las solicitudes en lotes en segundo plano si
+	// WRITE_BUFFER_ENABLED=true
+	WriteBuffer writeBufferConfig
+	// Fallback guarda en un fichero las solicitudes que no se pudieron
+	// insertar por estar caída la base de datos (FALLBACK_FILE)
+	Fallback fallbackConfig
+	// Purge borra definitivamente las solicitudes eliminadas hace más de
+	// PURGE_RETENTION (desactivada por defecto)
+	Purge purgeConfig
+
+	// EmptyFieldsPolicy decide cómo van los campos opcionales vacíos de las
+	// solicitudes en las respuestas (RESPONSE_EMPTY_FIELDS)
+	EmptyFieldsPolicy string
+
+	// CacheTTL es lo que se guardan /services y /stats/by-service (0 la
+	// desactiva)
+	CacheTTL time.Duration
+
+	// Seguridad
+	AdminAPIKey    string
+	AllowedOrigins []string
+	TrustedProxies []netip.Prefix
+
+	// CORSMaxAge es lo que el navegador guarda la respuesta al pre-flight;
+	// CORSAllowCredentials permite cookies y cabeceras de autenticación
+	// desde los orígenes de ALLOWED_ORIGINS
+	CORSMaxAge           time.Duration
+	CORSAllowCredentials bool
+
+	// WebhookURL recibe un POST con cada solicitud nueva (opcional)
+	WebhookURL string
+	// WebhookRetry configura los reintentos de los webhooks
+	// (WEBHOOK_MAX_ATTEMPTS, WEBHOOK_RETRY_BASE_DELAY y
+	// WEBHOOK_RETRY_MAX_TIME)
+	WebhookRetry webhookRetryConfig
+	// SMTP envía un correo con cada solicitud nueva si SMTP_HOST está
+	// definida
+	SMTP smtpConfig
+	// NotifyRoutes envía los avisos de algunos servicios a otros destinos
+	// (NOTIFY_ROUTES o NOTIFY_ROUTES_FILE)
+	NotifyRoutes map[string]notifyRoute
+	// SMSEnabled envía un SMS de confirmación al cliente con Twilio
+	SMSEnabled bool
+	Twilio     twilioConfig
+
+	// OutboundTimeout limita cada llamada a un servicio externo (webhooks,
+	// reCAPTCHA, Twilio, SMTP)
+	OutboundTimeout time.Duration
+
+	// MetricsEnabled expone /metrics para Prometheus
+	MetricsEnabled bool
+
+	// WelcomeMessage es el mensaje de la raíz de la API (WELCOME_MESSAGE) y
+	// DocsURL el enlace a la documentación que la acompaña (DOCS_URL)
+	WelcomeMessage string
+	DocsURL        string
+
+	// OTLPEndpoint activa el envío de trazas OpenTelemetry si
+	// OTEL_EXPORTER_OTLP_ENDPOINT está definida
+	OTLPEndpoint string
+
+	// Servidor HTTP; TLS solo se usa si hay certificado y clave
+	ListenAddr    string
+	Port          string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSMinVersion uint16
+	Timeouts      serverTimeouts
+	GzipMinSize   int
+}
+
+// Addr es la dirección host:puerto en la que escucha el servidor.
+func (c Config) Addr() string {
+	return net.JoinHostPort(c.ListenAddr, c.Port)
+}
+
+// UseTLS indica si el servidor debe servir HTTPS directamente.
+func (c Config) UseTLS() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// configErrors son todos los problemas encontrados por LoadConfig, para
+// corregirlos de una vez en lugar de uno por despliegue.
+type configErrors []error
+
+func (e configErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// LoadConfig lee y valida las variables de entorno. No se detiene en el
+// primer error: devuelve configErrors con todos, cada uno con el nombre de
+// la variable afectada.
+func LoadConfig() (Config, error) {
+	var c Config
+	var err error
+	var errs configErrors
+
+	if c.EnvFile, err = loadEnvFile(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if level := strings.TrimSpace(os.Getenv("LOG_LEVEL")); level != "" {
+		if err := c.LogLevel.UnmarshalText([]byte(level)); err != nil {
+			errs = append(errs, errors.New("LOG_LEVEL no es un nivel de log válido (debug, info, warn, error)"))
+		}
+	}
+
+	// --- Base de datos ---
+	// Railway inyecta la URL de la base de datos en una variable de entorno.
+	// DB_DRIVER elige la base de datos; por defecto MySQL.
+	c.DBDriver = os.Getenv("DB_DRIVER")
+	if c.DBDriver == "" {
+		c.DBDriver = driverMySQL
+	}
+	switch c.DBDriver {
+	case driverMySQL:
+		// Para MySQL en Railway, la variable de entorno es normalmente MYSQL_URL.
+		c.DatabaseURL = os.Getenv("MYSQL_URL")
+		if c.DatabaseURL == "" {
+			errs = append(errs, errors.New("La variable de entorno MYSQL_URL no está configurada. Asegúrate de que Railway la esté inyectando o configúrala localmente para pruebas."))
+		}
+	case driverPostgres:
+		c.DatabaseURL = os.Getenv("DATABASE_URL")
+		if c.DatabaseURL == "" {
+			errs = append(errs, errors.New("La variable de entorno DATABASE_URL es obligatoria con DB_DRIVER=postgres"))
+		}
+	case driverSQLite:
+		// Para SQLite, DATABASE_URL es la ruta del fichero
+		c.DatabaseURL = os.Getenv("DATABASE_URL")
+		if c.DatabaseURL == "" {
+			c.DatabaseURL = "pagemarmot.db"
+		}
+	default:
+		errs = append(errs, fmt.Errorf("DB_DRIVER debe ser \"mysql\", \"postgres\" o \"sqlite3\": %q", c.DBDriver))
+	}
+
+	maxOpenConns := defaultDBMaxOpenConns
+	if c.DBDriver == driverSQLite {
+		// SQLite solo admite un escritor a la vez
+		maxOpenConns = 1
+	}
+	if c.DBPool.MaxOpenConns, err = envInt("DB_MAX_OPEN_CONNS", maxOpenConns); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBPool.MaxIdleConns, err = envInt("DB_MAX_IDLE_CONNS", defaultDBMaxIdleConns); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBPool.MaxOpenConns < 0 || c.DBPool.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("DB_MAX_OPEN_CONNS y DB_MAX_IDLE_CONNS no pueden ser negativos"))
+	}
+	if c.DBPool.ConnMaxLifetime, err = envDuration("DB_CONN_MAX_LIFETIME", defaultDBConnMaxLifetime); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBRetry.MaxAttempts, err = envInt("DB_CONNECT_MAX_ATTEMPTS", defaultDBConnectAttempts); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBRetry.MaxAttempts < 1 {
+		errs = append(errs, errors.New("DB_CONNECT_MAX_ATTEMPTS debe ser al menos 1"))
+	}
+	if c.DBRetry.BaseDelay, err = envDuration("DB_CONNECT_BASE_DELAY", defaultDBConnectBaseDelay); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBQueryTimeout, err = envDuration("DB_QUERY_TIMEOUT", defaultDBQueryTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBReadBreaker.Threshold, err = envInt("DB_READ_BREAKER_THRESHOLD", defaultReadBreakerThreshold); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBReadBreaker.Cooldown, err = envDuration("DB_READ_BREAKER_COOLDOWN", defaultReadBreakerCooldown); err != nil {
+		errs = append(errs, err)
+	}
+	if c.DBReadBreaker.Threshold < 0 || c.DBReadBreaker.Cooldown <= 0 {
+		errs = append(errs, errors.New("DB_READ_BREAKER_THRESHOLD no puede ser negativo y DB_READ_BREAKER_COOLDOWN debe ser positiva"))
+	}
+	if c.MaintenanceMode, err = envBool("MAINTENANCE_MODE", false); err != nil {
+		errs = append(errs, err)
+	}
+
+	// --- Validación de solicitudes ---
+	c.TelefonoRegexp = telefonoRegexp
+	if pattern := os.Getenv("TELEFONO_REGEX"); pattern != "" {
+		if re, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("TELEFONO_REGEX no es una expresión regular válida: %v", err))
+		} else {
+			c.TelefonoRegexp = re
+		}
+	}
+	c.DefaultCountryCode = strings.TrimPrefix(strings.TrimSpace(os.Getenv("DEFAULT_COUNTRY_CODE")), "+")
+	if c.DefaultCountryCode != "" && (!isDigits(c.DefaultCountryCode) || len(c.DefaultCountryCode) > 3) {
+		errs = append(errs, errors.New("DEFAULT_COUNTRY_CODE debe ser un prefijo internacional como \"1\" o \"+34\""))
+	}
+	c.ServicioRegexp = servicioRegexp
+	if pattern := os.Getenv("SERVICIO_REGEX"); pattern != "" {
+		if re, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("SERVICIO_REGEX no es una expresión regular válida: %v", err))
+		} else {
+			c.ServicioRegexp = re
+		}
+	}
+	c.AllowedServices = envList("ALLOWED_SERVICES", defaultAllowedServices)
+	if len(c.AllowedServices) == 1 && c.AllowedServices[0] == "*" {
+		c.AllowedServices = nil
+	}
+	// Un servicio de la lista que no pase la validación no se podría pedir
+	for _, s := range c.AllowedServices {
+		n := utf8.RuneCountInString(s)
+		if n < minServicioLength || n > maxServicioLength || !c.ServicioRegexp.MatchString(s) {
+			errs = append(errs, fmt.Errorf("ALLOWED_SERVICES contiene un servicio que no cumple SERVICIO_REGEX o no tiene entre %d y %d caracteres: %q", minServicioLength, maxServicioLength, s))
+		}
+	}
+	c.HoneypotField = strings.TrimSpace(os.Getenv("HONEYPOT_FIELD"))
+	if c.HoneypotField == "" {
+		c.HoneypotField = defaultHoneypotField
+	}
+	switch c.HoneypotField {
+	case "nombre", "telefono", "servicio", "email", "mensaje", "horario_preferido":
+		errs = append(errs, fmt.Errorf("HONEYPOT_FIELD no puede ser un campo real de la solicitud: %q", c.HoneypotField))
+	}
+
+	maxBody, err := envInt("MAX_BODY_BYTES", defaultMaxBodyBytes)
+	if err != nil || maxBody < 1 {
+		errs = append(errs, errors.New("MAX_BODY_BYTES debe ser un número entero positivo"))
+	}
+	c.MaxBodyBytes = int64(maxBody)
+	c.MaxMensajeLength, err = envInt("MENSAJE_MAX_LENGTH", defaultMaxMensajeLength)
+	if err != nil || c.MaxMensajeLength < 1 {
+		errs = append(errs, errors.New("MENSAJE_MAX_LENGTH debe ser un número entero positivo"))
+	}
+	if c.StripHTML, err = envBool("STRIP_HTML", true); err != nil {
+		errs = append(errs, err)
+	}
+
+	c.RecaptchaSecret = strings.TrimSpace(os.Getenv("RECAPTCHA_SECRET"))
+	if c.RecaptchaMinScore, err = envFloat("RECAPTCHA_MIN_SCORE", defaultRecaptchaMinScore); err != nil {
+		errs = append(errs, err)
+	}
+	if c.RecaptchaMinScore < 0 || c.RecaptchaMinScore > 1 {
+		errs = append(errs, errors.New("RECAPTCHA_MIN_SCORE debe estar entre 0 y 1"))
+	}
+	if c.DedupWindow, err = envDuration("DEDUP_WINDOW", defaultDedupWindow); err != nil {
+		errs = append(errs, err)
+	}
+	c.DedupStrategy = strings.ToLower(strings.TrimSpace(os.Getenv("DEDUP_STRATEGY")))
+	switch c.DedupStrategy {
+	case "":
+		c.DedupStrategy = dedupSkip
+	case dedupSkip, dedupUpdate, dedupCount:
+	default:
+		errs = append(errs, fmt.Errorf("DEDUP_STRATEGY debe ser %s, %s o %s", dedupSkip, dedupUpdate, dedupCount))
+	}
+	if c.DailyQuotas, err = parseDailyQuotas(os.Getenv("SERVICE_DAILY_QUOTAS"), c.AllowedServices); err != nil {
+		errs = append(errs, err)
+	}
+	if c.IdempotencyTTL, err = envDuration("IDEMPOTENCY_TTL", defaultIdempotencyTTL); err != nil {
+		errs = append(errs, err)
+	}
+	if c.IdempotencyTTL <= 0 {
+		errs = append(errs, errors.New("IDEMPOTENCY_TTL debe ser una duración positiva"))
+	}
+
+	// --- Límite de peticiones ---
+	if c.RateLimitPerMinute, err = envFloat("RATE_LIMIT_PER_MINUTE", defaultRateLimitPerMinute); err != nil {
+		errs = append(errs, err)
+	}
+	if c.RateLimitBurst, err = envInt("RATE_LIMIT_BURST", defaultRateLimitBurst); err != nil {
+		errs = append(errs, err)
+	}
+	if c.RateLimitPerMinute <= 0 || c.RateLimitBurst < 1 {
+		errs = append(errs, errors.New("RATE_LIMIT_PER_MINUTE debe ser mayor que 0 y RATE_LIMIT_BURST al menos 1"))
+	}
+
+	c.Fallback.Path = strings.TrimSpace(os.Getenv("FALLBACK_FILE"))
+	if c.Fallback.ReplayInterval, err = envDuration("FALLBACK_REPLAY_INTERVAL", defaultFallbackReplayInterval); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Fallback.ReplayInterval <= 0 {
+		errs = append(errs, errors.New("FALLBACK_REPLAY_INTERVAL debe ser una duración positiva"))
+	}
+
+	if c.Purge.Retention, err = envDuration("PURGE_RETENTION", 0); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Purge.Interval, err = envDuration("PURGE_INTERVAL", defaultPurgeInterval); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Purge.BatchSize, err = envInt("PURGE_BATCH_SIZE", defaultPurgeBatchSize); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Purge.Retention < 0 || c.Purge.Interval <= 0 || c.Purge.BatchSize < 1 {
+		errs = append(errs, errors.New("PURGE_RETENTION no puede ser negativa, y PURGE_INTERVAL y PURGE_BATCH_SIZE deben ser positivos"))
+	}
+	if c.WriteBuffer.Enabled, err = envBool("WRITE_BUFFER_ENABLED", false); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WriteBuffer.Size, err = envInt("WRITE_BUFFER_SIZE", defaultWriteBufferSize); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WriteBuffer.BatchSize, err = envInt("WRITE_BUFFER_BATCH_SIZE", defaultWriteBufferBatchSize); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WriteBuffer.FlushInterval, err = envDuration("WRITE_BUFFER_FLUSH_INTERVAL", defaultWriteBufferFlushInterval); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WriteBuffer.Size < 1 || c.WriteBuffer.BatchSize < 1 || c.WriteBuffer.BatchSize > maxBulkImport || c.WriteBuffer.FlushInterval <= 0 {
+		errs = append(errs, fmt.Errorf("WRITE_BUFFER_SIZE debe ser al menos 1, WRITE_BUFFER_BATCH_SIZE estar entre 1 y %d y WRITE_BUFFER_FLUSH_INTERVAL ser positiva", maxBulkImport))
+	}
+
+	c.EmptyFieldsPolicy = strings.ToLower(strings.TrimSpace(os.Getenv("RESPONSE_EMPTY_FIELDS")))
+	switch c.EmptyFieldsPolicy {
+	case "":
+		c.EmptyFieldsPolicy = emptyFieldsDefault
+	case emptyFieldsDefault, emptyFieldsNull, emptyFieldsOmit:
+	default:
+		errs = append(errs, fmt.Errorf("RESPONSE_EMPTY_FIELDS debe ser %s, %s o %s", emptyFieldsDefault, emptyFieldsNull, emptyFieldsOmit))
+	}
+
+	if c.CacheTTL, err = envDuration("CACHE_TTL", defaultCacheTTL); err != nil {
+		errs = append(errs, err)
+	}
+
+	// --- Seguridad ---
+	c.AdminAPIKey = os.Getenv("ADMIN_API_KEY")
+	c.AllowedOrigins = envList("ALLOWED_ORIGINS", nil)
+	if c.TrustedProxies, err = parseTrustedProxies(envList("TRUSTED_PROXIES", defaultTrustedProxies)); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CORSMaxAge, err = envDuration("CORS_MAX_AGE", defaultCORSMaxAge); err != nil {
+		errs = append(errs, err)
+	}
+	if c.CORSMaxAge < 0 {
+		errs = append(errs, errors.New("CORS_MAX_AGE no puede ser negativa"))
+	}
+	if c.CORSAllowCredentials, err = envBool("CORS_ALLOW_CREDENTIALS", false); err != nil {
+		errs = append(errs, err)
+	}
+	// Los navegadores rechazan las credenciales con Allow-Origin "*"
+	if c.CORSAllowCredentials && len(c.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("CORS_ALLOW_CREDENTIALS=true requiere indicar los orígenes en ALLOWED_ORIGINS"))
+	}
+
+	// --- Notificaciones ---
+	c.WebhookURL = strings.TrimSpace(os.Getenv("WEBHOOK_URL"))
+	if c.WebhookURL != "" && !validHTTPURL(c.WebhookURL) {
+		errs = append(errs, errors.New("WEBHOOK_URL debe ser una URL http o https"))
+	}
+	if c.WebhookRetry.MaxAttempts, err = envInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WebhookRetry.BaseDelay, err = envDuration("WEBHOOK_RETRY_BASE_DELAY", defaultWebhookBaseDelay); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WebhookRetry.MaxElapsed, err = envDuration("WEBHOOK_RETRY_MAX_TIME", defaultWebhookMaxElapsed); err != nil {
+		errs = append(errs, err)
+	}
+	if c.WebhookRetry.MaxAttempts < 1 || c.WebhookRetry.MaxAttempts > maxWebhookAttempts {
+		errs = append(errs, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS debe estar entre 1 y %d", maxWebhookAttempts))
+	}
+	if c.WebhookRetry.BaseDelay < 0 || c.WebhookRetry.MaxElapsed <= 0 || c.WebhookRetry.MaxElapsed > notifyTimeout {
+		errs = append(errs, fmt.Errorf("WEBHOOK_RETRY_BASE_DELAY no puede ser negativa y WEBHOOK_RETRY_MAX_TIME debe ser positiva y no superar %s", notifyTimeout))
+	}
+
+	c.SMTP.Host = strings.TrimSpace(os.Getenv("SMTP_HOST"))
+	if c.SMTP.Host != "" {
+		if c.SMTP.Port, err = envInt("SMTP_PORT", defaultSMTPPort); err != nil {
+			errs = append(errs, err)
+		}
+		c.SMTP.User = os.Getenv("SMTP_USER")
+		c.SMTP.Password = os.Getenv("SMTP_PASS")
+		c.SMTP.From = strings.TrimSpace(os.Getenv("SMTP_FROM"))
+		c.SMTP.To = envList("SMTP_TO", nil)
+		if c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+			errs = append(errs, errors.New("Con SMTP_HOST configurada, SMTP_FROM y SMTP_TO son obligatorias"))
+		}
+	}
+
+	if c.NotifyRoutes, err = loadNotifyRoutes(c.AllowedServices); err != nil {
+		errs = append(errs, err)
+	}
+	if c.SMTP.Host == "" {
+		for servicio, route := range c.NotifyRoutes {
+			if len(route.Emails) > 0 {
+				errs = append(errs, fmt.Errorf("NOTIFY_ROUTES indica correos para %q pero SMTP_HOST no está configurada", servicio))
+			}
+		}
+	}
+
+	if c.SMSEnabled, err = envBool("SMS_ENABLED", false); err != nil {
+		errs = append(errs, err)
+	}
+	if c.SMSEnabled {
+		c.Twilio.AccountSID = strings.TrimSpace(os.Getenv("TWILIO_ACCOUNT_SID"))
+		c.Twilio.AuthToken = strings.TrimSpace(os.Getenv("TWILIO_AUTH_TOKEN"))
+		c.Twilio.From = strings.TrimSpace(os.Getenv("TWILIO_FROM"))
+		if c.Twilio.AccountSID == "" || c.Twilio.AuthToken == "" || c.Twilio.From == "" {
+			errs = append(errs, errors.New("Con SMS_ENABLED=true, TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN y TWILIO_FROM son obligatorias"))
+		}
+	}
+
+	if c.OutboundTimeout, err = envDuration("OUTBOUND_TIMEOUT", defaultOutboundTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	if c.OutboundTimeout <= 0 {
+		errs = append(errs, errors.New("OUTBOUND_TIMEOUT debe ser una duración positiva"))
+	}
+
+	if c.MetricsEnabled, err = envBool("METRICS_ENABLED", false); err != nil {
+		errs = append(errs, err)
+	}
+	c.OTLPEndpoint = strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+
+	c.WelcomeMessage = strings.TrimSpace(os.Getenv("WELCOME_MESSAGE"))
+	if c.WelcomeMessage == "" {
+		c.WelcomeMessage = defaultWelcomeMessage
+	}
+	c.DocsURL = strings.TrimSpace(os.Getenv("DOCS_URL"))
+	if c.DocsURL != "" && !validHTTPURL(c.DocsURL) {
+		errs = append(errs, errors.New("DOCS_URL debe ser una URL http o https"))
+	}
+
+	// --- Servidor HTTP ---
+	// Railway inyecta el puerto en PORT
+	c.Port = os.Getenv("PORT")
+	if c.Port == "" {
+		c.Port = "8080" // Puerto por defecto para desarrollo local
+	}
+	// LISTEN_ADDR limita la interfaz (por ejemplo 127.0.0.1 en desarrollo);
+	// por defecto todas las IPv4
+	c.ListenAddr = strings.TrimSpace(os.Getenv("LISTEN_ADDR"))
+	if c.ListenAddr == "" {
+		c.ListenAddr = defaultListenAddr
+	}
+	c.TLSCertFile, c.TLSKeyFile = os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE")
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		errs = append(errs, errors.New("TLS_CERT_FILE y TLS_KEY_FILE deben configurarse juntas"))
+	}
+	if c.UseTLS() {
+		if c.TLSMinVersion, err = tlsMinVersion(os.Getenv("TLS_MIN_VERSION")); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, t := range []struct {
+		name  string
+		value *time.Duration
+		def   time.Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", &c.Timeouts.ReadHeader, defaultReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", &c.Timeouts.Read, defaultReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", &c.Timeouts.Write, defaultWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &c.Timeouts.Idle, defaultIdleTimeout},
+	} {
+		if *t.value, err = envDuration(t.name, t.def); err != nil {
+			errs = append(errs, err)
+		}
+		if *t.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s debe ser una duración positiva", t.name))
+		}
+	}
+	if c.Timeouts.Request, err = envDuration("REQUEST_TIMEOUT", defaultRequestTimeout); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Timeouts.Request < 0 {
+		errs = append(errs, errors.New("REQUEST_TIMEOUT no puede ser negativa"))
+	}
+	c.GzipMinSize, err = envInt("GZIP_MIN_SIZE", defaultGzipMinSize)
+	if err != nil || c.GzipMinSize < 0 {
+		errs = append(errs, errors.New("GZIP_MIN_SIZE debe ser un número entero no negativo"))
+	}
+
+	if len(errs) > 0 {
+		return c, errs
+	}
+	return c, nil
+}
+
+// loadEnvFile carga el fichero indicado en ENV_FILE (por defecto .env) si
+// existe. Solo define las variables que no estén ya en el entorno, así que
+// las del despliegue siempre tienen prioridad.
+func loadEnvFile() (string, error) {
+	path := os.Getenv("ENV_FILE")
+	if path == "" {
+		path = defaultEnvFile
+	}
+	if err := godotenv.Load(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("Error al leer ENV_FILE %q: %v", path, err)
+	}
+	return path, nil
+}
+
+// apply copia la configuración a las variables globales que usan los
+// handlers.
+func (c Config) apply() {
+	dbDriver = c.DBDriver
+	dbQueryTimeout = c.DBQueryTimeout
+	dbMaxIdleConns = c.DBPool.MaxIdleConns
+	telefonoRegexp = c.TelefonoRegexp
+	servicioRegexp = c.ServicioRegexp
+	defaultCountryCode = c.DefaultCountryCode
+	allowedServices = c.AllowedServices
+	honeypotField = c.HoneypotField
+	// Antes de crear el verificador y los avisos, que usan el cliente
+	outboundTimeout = c.OutboundTimeout
+	outboundClient = newOutboundClient(c.OutboundTimeout)
+	captchaVerifier = nil
+	if c.RecaptchaSecret != "" {
+		captchaVerifier = newRecaptchaVerifier(c.RecaptchaSecret, c.RecaptchaMinScore)
+	}
+	maxBodyBytes = c.MaxBodyBytes
+	gzipMinSize = c.GzipMinSize
+	maxMensajeLength = c.MaxMensajeLength
+	stripHTML = c.StripHTML
+	dedupWindow = c.DedupWindow
+	dedupStrategy = c.DedupStrategy
+	dailyQuotas = c.DailyQuotas
+	emptyFieldsPolicy = c.EmptyFieldsPolicy
+	idempotencyTTL = c.IdempotencyTTL
+	adminAPIKey = c.AdminAPIKey
+	allowedOrigins = c.AllowedOrigins
+	trustedProxies = c.TrustedProxies
+	corsMaxAge = c.CORSMaxAge
+	corsAllowCredentials = c.CORSAllowCredentials
+	responseCache = newTTLCache(c.CacheTTL)
+	readBreaker = newCircuitBreaker(c.DBReadBreaker)
+	maintenanceMode.Store(c.MaintenanceMode)
+	welcomeMessage = c.WelcomeMessage
+	docsURL = c.DocsURL
+
+	webhookRetry = c.WebhookRetry
+	notifiers, serviceNotifiers = buildNotifiers(c)
+}
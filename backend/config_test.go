@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setTestEnv deja un entorno mínimo válido para LoadConfig, sin leer el
+// .env del directorio, y aplica vars encima.
+func setTestEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+	t.Setenv("ENV_FILE", filepath.Join(t.TempDir(), "no-existe.env"))
+	t.Setenv("DB_DRIVER", driverSQLite)
+	t.Setenv("DATABASE_URL", filepath.Join(t.TempDir(), "test.db"))
+	for k, v := range vars {
+		t.Setenv(k, v)
+	}
+}
+
+func TestLoadConfigRejectsNegativePoolSizes(t *testing.T) {
+	const msg = "DB_MAX_OPEN_CONNS y DB_MAX_IDLE_CONNS no pueden ser negativos"
+	for _, tc := range []struct {
+		name    string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{"por defecto", nil, false},
+		{"cero", map[string]string{"DB_MAX_OPEN_CONNS": "0", "DB_MAX_IDLE_CONNS": "0"}, false},
+		{"open negativo", map[string]string{"DB_MAX_OPEN_CONNS": "-1"}, true},
+		{"idle negativo", map[string]string{"DB_MAX_IDLE_CONNS": "-5"}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestEnv(t, tc.vars)
+			_, err := LoadConfig()
+			got := err != nil && strings.Contains(err.Error(), msg)
+			if got != tc.wantErr {
+				t.Errorf("error = %v, want error de pool %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigListenAddr(t *testing.T) {
+	for _, tc := range []struct {
+		listenAddr, port, want string
+	}{
+		{"", "", "0.0.0.0:8080"},
+		{"", "3000", "0.0.0.0:3000"},
+		{"127.0.0.1", "3000", "127.0.0.1:3000"},
+		{"::1", "3000", "[::1]:3000"},
+	} {
+		setTestEnv(t, map[string]string{"LISTEN_ADDR": tc.listenAddr, "PORT": tc.port})
+		c, err := LoadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.Addr(); got != tc.want {
+			t.Errorf("LISTEN_ADDR=%q PORT=%q: Addr() = %q, want %q", tc.listenAddr, tc.port, got, tc.want)
+		}
+	}
+}
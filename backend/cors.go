@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// allowedOrigins son los orígenes que pueden llamar a la API desde el
+// navegador (ALLOWED_ORIGINS). Si está vacío se permite cualquier origen,
+// lo que solo es adecuado para desarrollo local.
+var allowedOrigins []string
+
+// Tiempo por defecto que el navegador guarda el pre-flight (CORS_MAX_AGE)
+const defaultCORSMaxAge = 600 * time.Second
+
+var corsMaxAge = defaultCORSMaxAge
+
+// corsAllowCredentials permite peticiones con credenciales
+// (CORS_ALLOW_CREDENTIALS). LoadConfig exige ALLOWED_ORIGINS, porque con
+// credenciales Access-Control-Allow-Origin no puede ser "*".
+var corsAllowCredentials bool
+
+// corsMiddleware añade las cabeceras CORS y responde a los pre-flight
+// (OPTIONS) de todas las rutas, con Access-Control-Max-Age para que el
+// navegador no repita el pre-flight en cada petición.
+func corsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := allowedOrigin(r.Header.Get("Origin")); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-API-Key, X-Requested-With, X-Request-ID, Idempotency-Key, If-None-Match, If-Modified-Since")
+			w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Deprecation, Link, Idempotent-Replayed, ETag, Last-Modified")
+			if corsAllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if r.Method == "OPTIONS" {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			}
+		}
+		if len(allowedOrigins) > 0 {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		// Manejar pre-flight requests (OPTIONS)
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedOrigin devuelve el valor de Access-Control-Allow-Origin para el
+// origen de la petición, o "" si no se le debe permitir el acceso.
+func allowedOrigin(origin string) string {
+	if len(allowedOrigins) == 0 {
+		return "*"
+	}
+	if origin != "" && slices.Contains(allowedOrigins, origin) {
+		return origin
+	}
+	return ""
+}
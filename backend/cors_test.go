@@ -0,0 +1,130 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// withCORS fija la configuración de CORS mientras dura el test.
+func withCORS(t *testing.T, origins []string, maxAge time.Duration, credentials bool) {
+	t.Helper()
+	prevOrigins, prevMaxAge, prevCredentials := allowedOrigins, corsMaxAge, corsAllowCredentials
+	allowedOrigins, corsMaxAge, corsAllowCredentials = origins, maxAge, credentials
+	t.Cleanup(func() { allowedOrigins, corsMaxAge, corsAllowCredentials = prevOrigins, prevMaxAge, prevCredentials })
+}
+
+// serveCORS pasa una petición con Origin por corsMiddleware; next responde
+// 204 para distinguirla de la respuesta al pre-flight.
+func serveCORS(method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/v1/submit-service", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == "OPTIONS" {
+		req.Header.Set("Access-Control-Request-Method", "POST")
+	}
+	rec := httptest.NewRecorder()
+	corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflight(t *testing.T) {
+	withCORS(t, nil, defaultCORSMaxAge, false)
+	rec := serveCORS("OPTIONS", "https://example.com")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "*",
+		"Access-Control-Max-Age":           "600",
+		"Access-Control-Allow-Credentials": "",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+	if methods := rec.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(methods, "POST") {
+		t.Errorf("Access-Control-Allow-Methods = %q, falta POST", methods)
+	}
+	if allowed := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(allowed, "Idempotency-Key") {
+		t.Errorf("Access-Control-Allow-Headers = %q, falta Idempotency-Key", allowed)
+	}
+}
+
+func TestCORSPreflightWithCredentials(t *testing.T) {
+	withCORS(t, []string{"https://raynertec.com"}, 2*time.Hour, true)
+	rec := serveCORS("OPTIONS", "https://raynertec.com")
+
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":      "https://raynertec.com",
+		"Access-Control-Max-Age":           "7200",
+		"Access-Control-Allow-Credentials": "true",
+		"Vary":                             "Origin",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCORSRejectsUnknownOrigin(t *testing.T) {
+	withCORS(t, []string{"https://raynertec.com"}, defaultCORSMaxAge, true)
+	rec := serveCORS("OPTIONS", "https://otro.example")
+
+	for _, header := range []string{"Access-Control-Allow-Origin", "Access-Control-Allow-Credentials", "Access-Control-Max-Age"} {
+		if got := rec.Header().Get(header); got != "" {
+			t.Errorf("%s = %q para un origen no permitido", header, got)
+		}
+	}
+}
+
+func TestCORSMaxAgeOnlyOnPreflight(t *testing.T) {
+	withCORS(t, []string{"https://raynertec.com"}, defaultCORSMaxAge, true)
+	rec := serveCORS("POST", "https://raynertec.com")
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204 del handler", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q fuera del pre-flight", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+}
+
+func TestLoadConfigCORS(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		vars    map[string]string
+		wantErr string
+	}{
+		{"por defecto", nil, ""},
+		{"max-age negativa", map[string]string{"CORS_MAX_AGE": "-1s"}, "CORS_MAX_AGE no puede ser negativa"},
+		{"credenciales sin orígenes", map[string]string{"CORS_ALLOW_CREDENTIALS": "true"}, "CORS_ALLOW_CREDENTIALS=true requiere indicar los orígenes en ALLOWED_ORIGINS"},
+		{"credenciales con orígenes", map[string]string{"CORS_ALLOW_CREDENTIALS": "true", "ALLOWED_ORIGINS": "https://raynertec.com"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestEnv(t, tc.vars)
+			cfg, err := LoadConfig()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig = %v", err)
+				}
+				if tc.vars == nil && cfg.CORSMaxAge != defaultCORSMaxAge {
+					t.Errorf("CORSMaxAge = %s, want %s", cfg.CORSMaxAge, defaultCORSMaxAge)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("LoadConfig = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
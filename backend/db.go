@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"github.com/go-sql-driver/mysql" // <--- Driver para MySQL
+	"github.com/lib/pq"
+)
+
+// Drivers de base de datos admitidos (DB_DRIVER). SQLite es para desarrollo
+// local y solo está disponible si se compila con -tags sqlite.
+const (
+	driverMySQL    = "mysql"
+	driverPostgres = "postgres"
+	driverSQLite   = "sqlite3"
+)
+
+// dbDriver es el driver con el que se abrió db. Las consultas se escriben
+// con marcadores "?" y rebind las adapta al driver.
+var dbDriver = driverMySQL
+
+// Valores por defecto del pool de conexiones, pensados para los planes
+// pequeños de MySQL en Railway.
+const (
+	defaultDBMaxOpenConns    = 10
+	defaultDBMaxIdleConns    = 5
+	defaultDBConnMaxLifetime = 5 * time.Minute
+)
+
+// dbMaxIdleConns es el MaxIdleConns configurado, para restaurarlo después
+// de vaciar el pool en una reconexión.
+var dbMaxIdleConns = defaultDBMaxIdleConns
+
+// Reintentos de la conexión inicial: Railway a veces arranca el servicio
+// antes de que MySQL esté listo.
+const (
+	defaultDBConnectAttempts  = 10
+	defaultDBConnectBaseDelay = time.Second
+	maxDBConnectDelay         = 30 * time.Second
+)
+
+// Tiempo máximo por defecto de las consultas hechas desde los handlers
+const defaultDBQueryTimeout = 5 * time.Second
+
+// dbQueryTimeout es el tiempo máximo de las consultas de cada petición
+// (DB_QUERY_TIMEOUT).
+var dbQueryTimeout = defaultDBQueryTimeout
+
+// dbRetryConfig controla los reintentos del ping inicial con backoff
+// exponencial: la espera se duplica en cada intento hasta maxDBConnectDelay.
+type dbRetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+// dbPoolConfig son los límites del pool de conexiones de database/sql.
+type dbPoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// openDB abre la conexión con el driver indicado, comprueba que responde y
+// aplica las migraciones pendientes.
+func openDB(driver, dbURL string, pool dbPoolConfig, retry dbRetryConfig) (*sql.DB, error) {
+	if !slices.Contains(sql.Drivers(), driver) {
+		return nil, fmt.Errorf("El driver %q no está incluido en este binario (SQLite requiere compilar con -tags sqlite)", driver)
+	}
+
+	dbURL, err := normalizeDSN(driver, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("La URL de la base de datos no es válida: %v", err)
+	}
+
+	// Abre la conexión a la base de datos. otelsql crea un span por consulta,
+	// hijo del de la petición; sin OTEL_EXPORTER_OTLP_ENDPOINT no hace nada
+	conn, err := otelsql.Open(driver, dbURL,
+		otelsql.WithAttributes(dbSystemAttribute(driver)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{DisableErrSkip: true, OmitConnResetSession: true, OmitRows: true}))
+	if err != nil {
+		return nil, fmt.Errorf("Error al conectar a la base de datos: %v", err)
+	}
+	conn.SetMaxOpenConns(pool.MaxOpenConns)
+	conn.SetMaxIdleConns(pool.MaxIdleConns)
+	conn.SetConnMaxLifetime(pool.ConnMaxLifetime)
+
+	// Prueba la conexión
+	if err := pingWithRetry(conn, retry); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error al hacer ping a la base de datos tras %d intentos: %v", retry.MaxAttempts, err)
+	}
+
+	// --- Crear o actualizar el esquema ---
+	if err := runMigrations(conn, driver); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("Error al aplicar las migraciones: %v", err)
+	}
+
+	return conn, nil
+}
+
+// normalizeDSN completa la cadena de conexión con los parámetros que la
+// aplicación necesita, sin tocar los que ya vengan:
+//   - la sesión trabaja en UTC para que los TIMESTAMP se lean y se comparen
+//     sin depender de la zona horaria del servidor;
+//   - en MySQL, parseTime=true y utf8mb4, para que las tildes y la ñ de
+//     nombre no lleguen rotas.
+func normalizeDSN(driver, dsn string) (string, error) {
+	switch driver {
+	case driverMySQL:
+		return normalizeMySQLDSN(dsn)
+	case driverPostgres:
+		if strings.Contains(strings.ToLower(dsn), "timezone=") {
+			return dsn, nil
+		}
+		if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+			q := u.Query()
+			q.Set("timezone", "UTC")
+			u.RawQuery = q.Encode()
+			return u.String(), nil
+		}
+		// Formato clave=valor
+		return dsn + " timezone=UTC", nil
+	}
+	// SQLite guarda CURRENT_TIMESTAMP siempre en UTC
+	return dsn, nil
+}
+
+// Juego de caracteres y collation de la conexión MySQL si MYSQL_URL no los
+// indica.
+const (
+	mysqlCharset   = "utf8mb4"
+	mysqlCollation = "utf8mb4_unicode_ci"
+)
+
+func normalizeMySQLDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	// Con parseTime el driver devuelve time.Time en la zona loc, que por
+	// defecto es UTC
+	cfg.ParseTime = true
+	// upsertSolicitud distingue por las filas afectadas si el INSERT creó
+	// una fila o chocó con otra sin cambiarla
+	cfg.ClientFoundRows = false
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	if _, ok := cfg.Params["time_zone"]; !ok {
+		cfg.Params["time_zone"] = "'+00:00'"
+	}
+
+	// mysql.Config no expone el charset, así que se mira en la cadena
+	params := dsnParams(dsn)
+	if !params.Has("charset") && !params.Has("collation") {
+		if err := cfg.Apply(mysql.Charset(mysqlCharset, mysqlCollation)); err != nil {
+			return "", err
+		}
+	}
+	return cfg.FormatDSN(), nil
+}
+
+// dsnParams devuelve los parámetros de la parte "?..." de un DSN de MySQL.
+func dsnParams(dsn string) url.Values {
+	i := strings.LastIndex(dsn, "?")
+	if i < 0 {
+		return url.Values{}
+	}
+	params, _ := url.ParseQuery(dsn[i+1:])
+	return params
+}
+
+// redactDSN oculta la contraseña de la cadena de conexión para poder
+// escribirla en los logs.
+func redactDSN(driver, dsn string) string {
+	switch driver {
+	case driverMySQL:
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "(no válida)"
+		}
+		if cfg.Passwd != "" {
+			cfg.Passwd = "xxxxx"
+		}
+		return cfg.FormatDSN()
+	case driverPostgres:
+		if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+			return u.Redacted()
+		}
+		// En formato clave=valor solo se muestra que está configurada
+		return "(clave=valor)"
+	}
+	return dsn
+}
+
+// querier lo cumplen *sql.DB y *sql.Tx, para que las funciones de acceso a
+// datos puedan usarse dentro o fuera de una transacción.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx ejecuta fn dentro de una transacción y hace commit si no devuelve
+// error; si lo devuelve, deshace los cambios y devuelve ese error. Si la
+// conexión se cae antes del commit, reconecta y repite la transacción una
+// vez, así que fn debe poder ejecutarse de nuevo desde el principio.
+func withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	committing := false
+	run := func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		committing = true
+		return tx.Commit()
+	}
+
+	err := run()
+	// Si la conexión se cae durante el commit no se sabe si los cambios se
+	// guardaron, así que en ese caso no se repite
+	if committing || !reconnectOnBadConn(ctx, err) {
+		return err
+	}
+	return run()
+}
+
+// retryOnBadConn ejecuta fn y, si falla porque la conexión con la base de
+// datos se cayó, reconecta y la repite una vez. Solo debe usarse con
+// operaciones que se puedan repetir sin efectos duplicados.
+func retryOnBadConn(ctx context.Context, fn func() error) error {
+	err := fn()
+	if !reconnectOnBadConn(ctx, err) {
+		return err
+	}
+	return fn()
+}
+
+// isBadConnError indica si err se debe a una conexión rota, por ejemplo
+// después de que Railway reinicie MySQL.
+func isBadConnError(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn)
+}
+
+// isDuplicateKeyError indica si err es una violación de clave primaria o
+// única, por ejemplo al insertar dos veces la misma Idempotency-Key.
+func isDuplicateKeyError(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062 // ER_DUP_ENTRY
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505" // unique_violation
+	}
+	// El driver de SQLite solo se compila con -tags sqlite, así que no se
+	// puede usar su tipo de error
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// reconnectOnBadConn comprueba si err es de conexión rota y, en ese caso,
+// descarta las conexiones inactivas del pool (probablemente también muertas)
+// y hace ping para abrir una nueva. Devuelve true si merece la pena
+// reintentar la operación.
+func reconnectOnBadConn(ctx context.Context, err error) bool {
+	if !isBadConnError(err) || ctx.Err() != nil {
+		return false
+	}
+	logger.Warn("Conexión con la base de datos perdida, reconectando", "error", err)
+
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(dbMaxIdleConns)
+	if pingErr := db.PingContext(ctx); pingErr != nil {
+		logger.Error("No se pudo reconectar con la base de datos", "error", pingErr)
+		return false
+	}
+	logger.Info("Reconexión con la base de datos completada")
+	return true
+}
+
+// rebind convierte los marcadores "?" de una consulta al estilo del driver
+// ($1, $2, ... en Postgres). Las consultas no deben llevar "?" dentro de
+// literales de texto.
+func rebind(query string) string {
+	if dbDriver != driverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// pingWithRetry hace ping a la base de datos hasta que responde o se agotan
+// los intentos, esperando cada vez el doble que la anterior.
+func pingWithRetry(conn *sql.DB, retry dbRetryConfig) error {
+	delay := retry.BaseDelay
+	var err error
+	for attempt := 1; attempt <= retry.MaxAttempts; attempt++ {
+		if err = conn.Ping(); err == nil {
+			return nil
+		}
+		if attempt == retry.MaxAttempts {
+			break
+		}
+		logger.Warn("La base de datos no responde, reintentando",
+			"attempt", attempt, "max_attempts", retry.MaxAttempts, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxDBConnectDelay)
+	}
+	return err
+}
+
+// dbContext devuelve el contexto para las consultas de una petición: se
+// cancela si el cliente aborta y expira tras dbQueryTimeout.
+func dbContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), dbQueryTimeout)
+}
+
+// writeDBError registra un error de base de datos y responde 504 si la
+// consulta superó dbQueryTimeout, o 500 con message en cualquier otro caso.
+func writeDBError(w http.ResponseWriter, r *http.Request, err error, message string, logArgs ...any) {
+	status, code := http.StatusInternalServerError, codeInternal
+	if errors.Is(err, context.DeadlineExceeded) {
+		status, code = http.StatusGatewayTimeout, codeDBTimeout
+		message = "La base de datos tardó demasiado en responder"
+	}
+	requestLogger(r).Error(message, append(logArgs, "error", err, "status", status)...)
+	writeError(w, status, code, message)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// badConnDB es un driver falso cuyas operaciones fallan con
+// driver.ErrBadConn las veces indicadas, como tras un reinicio de MySQL.
+type badConnDB struct {
+	mu          sync.Mutex
+	execFails   int // Exec dentro de una transacción
+	commitFails int
+	execs       int
+	commits     int
+}
+
+func (d *badConnDB) Connect(context.Context) (driver.Conn, error) { return &badConnConn{d}, nil }
+func (d *badConnDB) Driver() driver.Driver                        { return nil }
+
+// fail descuenta uno de *n y devuelve driver.ErrBadConn si quedaba alguno.
+func (d *badConnDB) fail(n *int) error {
+	if *n > 0 {
+		*n--
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+type badConnConn struct{ d *badConnDB }
+
+func (c *badConnConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("no soportado") }
+func (c *badConnConn) Close() error                        { return nil }
+func (c *badConnConn) Begin() (driver.Tx, error)           { return c, nil }
+
+func (c *badConnConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.execs++
+	if err := c.d.fail(&c.d.execFails); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *badConnConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.commits++
+	return c.d.fail(&c.d.commitFails)
+}
+
+func (c *badConnConn) Rollback() error { return nil }
+
+// useBadConnDB deja en db el driver falso mientras dura el test.
+func useBadConnDB(t *testing.T, fake *badConnDB) {
+	t.Helper()
+	conn := sql.OpenDB(fake)
+	prevDB := db
+	db = conn
+	t.Cleanup(func() {
+		conn.Close()
+		db = prevDB
+	})
+}
+
+func TestWithTxRetriesOnceOnBadConn(t *testing.T) {
+	fake := &badConnDB{execFails: 1}
+	useBadConnDB(t, fake)
+
+	calls := 0
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("withTx = %v", err)
+	}
+	if calls != 2 || fake.commits != 1 {
+		t.Errorf("fn llamada %d veces y %d commits, want 2 y 1", calls, fake.commits)
+	}
+}
+
+func TestWithTxGivesUpAfterOneRetry(t *testing.T) {
+	fake := &badConnDB{execFails: 5}
+	useBadConnDB(t, fake)
+
+	calls := 0
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("withTx = %v, want driver.ErrBadConn", err)
+	}
+	if calls != 2 {
+		t.Errorf("fn llamada %d veces, want 2", calls)
+	}
+}
+
+func TestWithTxDoesNotRetryFailedCommit(t *testing.T) {
+	fake := &badConnDB{commitFails: 1}
+	useBadConnDB(t, fake)
+
+	calls := 0
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		_, err := tx.Exec("INSERT")
+		return err
+	})
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Errorf("withTx = %v, want driver.ErrBadConn", err)
+	}
+	// No se sabe si el commit llegó a guardarse: repetirlo podría duplicar
+	if calls != 1 || fake.execs != 1 || fake.commits != 1 {
+		t.Errorf("fn llamada %d veces, %d execs y %d commits, want 1", calls, fake.execs, fake.commits)
+	}
+}
+
+func TestWithTxDoesNotRetryOtherErrors(t *testing.T) {
+	useBadConnDB(t, &badConnDB{})
+
+	errInvalid := errors.New("datos inválidos")
+	calls := 0
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		calls++
+		return errInvalid
+	})
+	if !errors.Is(err, errInvalid) || calls != 1 {
+		t.Errorf("withTx = %v tras %d llamadas, want %v tras 1", err, calls, errInvalid)
+	}
+}
+
+func TestRetryOnBadConn(t *testing.T) {
+	useBadConnDB(t, &badConnDB{})
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, tc := range []struct {
+		name      string
+		ctx       context.Context
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"éxito", context.Background(), []error{nil}, 1, nil},
+		{"conexión rota una vez", context.Background(), []error{driver.ErrBadConn, nil}, 2, nil},
+		{"conexión rota siempre", context.Background(), []error{driver.ErrBadConn, driver.ErrBadConn, nil}, 2, driver.ErrBadConn},
+		{"otro error", context.Background(), []error{sql.ErrNoRows, nil}, 1, sql.ErrNoRows},
+		{"contexto cancelado", canceled, []error{driver.ErrBadConn, nil}, 1, driver.ErrBadConn},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			err := retryOnBadConn(tc.ctx, func() error {
+				calls++
+				return tc.errs[calls-1]
+			})
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("retryOnBadConn = %v, want %v", err, tc.wantErr)
+			}
+			if calls != tc.wantCalls {
+				t.Errorf("fn llamada %d veces, want %d", calls, tc.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithTxRollsBackOnError(t *testing.T) {
+	openTestDB(t)
+	errFail := errors.New("fallo simulado")
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		if _, err := insertSolicitud(context.Background(), tx, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"}, clientInfo{}); err != nil {
+			return err
+		}
+		return errFail
+	})
+	if !errors.Is(err, errFail) {
+		t.Errorf("withTx = %v, want %v", err, errFail)
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("quedaron %d solicitudes guardadas", n)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ventana por defecto para considerar duplicada una solicitud idéntica
+// (doble clic en el botón de enviar).
+const defaultDedupWindow = 60 * time.Second
+
+// dedupWindow es la ventana configurada con DEDUP_WINDOW; 0 desactiva la
+// detección de duplicados.
+var dedupWindow = defaultDedupWindow
+
+// Qué se hace con la solicitud existente al recibir un duplicado
+// (DEDUP_STRATEGY). En todos los casos se devuelve la existente y no se
+// inserta otra.
+const (
+	// dedupSkip la deja como está
+	dedupSkip = "skip"
+	// dedupUpdate mueve su fecha_creacion a ahora
+	dedupUpdate = "update"
+	// dedupCount suma uno a su submission_count
+	dedupCount = "count"
+)
+
+var dedupStrategy = dedupSkip
+
+// findRecentDuplicate busca una solicitud con el mismo nombre, teléfono y
+// servicio creada dentro de dedupWindow. Como dedupKey, no distingue
+// mayúsculas: si no, el índice único devolvería como duplicado lo que aquí
+// no lo es. Devuelve false si no hay ninguna.
+func findRecentDuplicate(ctx context.Context, q querier, s Solicitud) (SolicitudGuardada, bool, error) {
+	if dedupWindow <= 0 {
+		return SolicitudGuardada{}, false, nil
+	}
+
+	since := time.Now().UTC().Add(-dedupWindow)
+	dup, err := scanSolicitud(q.QueryRowContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes
+		WHERE LOWER(nombre) = LOWER(?) AND LOWER(telefono) = LOWER(?) AND LOWER(servicio) = LOWER(?) AND fecha_creacion >= ? AND deleted_at IS NULL
+		ORDER BY id DESC LIMIT 1`), s.Nombre, s.Telefono, s.Servicio, since))
+	if errors.Is(err, sql.ErrNoRows) {
+		return dup, false, nil
+	}
+	if err != nil {
+		return dup, false, err
+	}
+	return dup, true, nil
+}
+
+// resolveDuplicate aplica dedupStrategy a la solicitud duplicada que
+// encontró findRecentDuplicate y la devuelve como queda.
+func resolveDuplicate(ctx context.Context, tx *sql.Tx, dup SolicitudGuardada) (SolicitudGuardada, error) {
+	var query string
+	switch dedupStrategy {
+	case dedupUpdate:
+		query = `UPDATE solicitudes SET fecha_creacion = CURRENT_TIMESTAMP WHERE id = ?`
+	case dedupCount:
+		query = `UPDATE solicitudes SET submission_count = submission_count + 1 WHERE id = ?`
+	default:
+		return dup, nil
+	}
+	// Sin execOne: MySQL cuenta 0 filas afectadas si la fecha no cambia
+	// porque el duplicado llega en el mismo segundo
+	if _, err := tx.ExecContext(ctx, rebind(query), dup.ID); err != nil {
+		return dup, err
+	}
+	return scanSolicitud(tx.QueryRowContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes WHERE id = ?`), dup.ID))
+}
+
+// dedupKey es la huella de los campos que hacen que dos solicitudes sean la
+// misma, para el índice único de solicitudes.dedup_key. Ignora mayúsculas
+// como la collation de MySQL.
+func dedupKey(s Solicitud) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(s.Nombre + "\x00" + s.Telefono + "\x00" + s.Servicio)))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupUpdates son las columnas que cambia cada estrategia cuando el INSERT
+// choca con una solicitud idéntica.
+var dedupUpdates = map[string]string{
+	dedupUpdate: "fecha_creacion = CURRENT_TIMESTAMP",
+	dedupCount:  "submission_count = solicitudes.submission_count + 1",
+}
+
+// upsertSolicitud inserta s con su dedup_key. Si otra petición idéntica la
+// guardó entre findRecentDuplicate y el INSERT, el índice único lo detecta
+// y, con INSERT ... ON DUPLICATE KEY UPDATE (ON CONFLICT en Postgres y
+// SQLite), se aplica dedupStrategy a esa en lugar de crear otra. Devuelve
+// el id de la solicitud y si es nueva.
+func upsertSolicitud(ctx context.Context, tx *sql.Tx, s Solicitud, client clientInfo) (int64, bool, error) {
+	if dedupWindow <= 0 {
+		id, err := insertSolicitud(ctx, tx, s, client)
+		return id, true, err
+	}
+
+	// Las solicitudes fuera de la ventana o eliminadas ya no cuentan como
+	// duplicadas: sueltan la clave para que la nueva pueda usarla
+	key := dedupKey(s)
+	since := time.Now().UTC().Add(-dedupWindow)
+	if _, err := tx.ExecContext(ctx, rebind(`UPDATE solicitudes SET dedup_key = NULL
+		WHERE dedup_key = ? AND (fecha_creacion < ? OR deleted_at IS NOT NULL)`), key, since); err != nil {
+		return 0, false, err
+	}
+
+	const insertSQL = `INSERT INTO solicitudes (` + insertColumns + `, dedup_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := append(insertArgs(s, client), key)
+	set, ok := dedupUpdates[dedupStrategy]
+	if !ok {
+		// skip: una asignación que no cambia nada, para que RETURNING
+		// devuelva la fila existente
+		set = "dedup_key = excluded.dedup_key"
+	}
+
+	var (
+		id       int64
+		inserted bool
+	)
+	switch dbDriver {
+	case driverPostgres:
+		// xmax es 0 en las filas recién insertadas
+		err := tx.QueryRowContext(ctx, rebind(insertSQL+` ON CONFLICT (dedup_key) DO UPDATE SET `+set+` RETURNING id, xmax = 0`), args...).Scan(&id, &inserted)
+		if err != nil {
+			return 0, false, err
+		}
+	case driverSQLite:
+		// RETURNING no dice si la fila es nueva, pero SQLite solo admite un
+		// escritor: si el id supera el máximo de antes, se insertó
+		var maxID int64
+		if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM solicitudes`).Scan(&maxID); err != nil {
+			return 0, false, err
+		}
+		err := tx.QueryRowContext(ctx, insertSQL+` ON CONFLICT (dedup_key) DO UPDATE SET `+set+` RETURNING id`, args...).Scan(&id)
+		if err != nil {
+			return 0, false, err
+		}
+		inserted = id > maxID
+	default:
+		// LAST_INSERT_ID(id) hace que LastInsertId devuelva la fila
+		// existente. MySQL cuenta 1 fila afectada si inserta y 2 o 0 si
+		// actualiza (normalizeMySQLDSN desactiva clientFoundRows)
+		set = "id = LAST_INSERT_ID(id)"
+		if update, ok := dedupUpdates[dedupStrategy]; ok {
+			set += ", " + update
+		}
+		result, err := tx.ExecContext(ctx, insertSQL+` ON DUPLICATE KEY UPDATE `+set, args...)
+		if err != nil {
+			return 0, false, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, false, err
+		}
+		if id, err = result.LastInsertId(); err != nil {
+			return 0, false, err
+		}
+		inserted = affected == 1
+	}
+	return id, inserted, nil
+}
+
+// lockDuplicate lee la solicitud con la que chocó upsertSolicitud. En MySQL
+// se bloquea la fila porque la lectura normal usa la foto del inicio de la
+// transacción, en la que aún no estaba.
+func lockDuplicate(ctx context.Context, tx *sql.Tx, id int64) (SolicitudGuardada, error) {
+	query := `SELECT ` + solicitudColumns + ` FROM solicitudes WHERE id = ?`
+	if dbDriver != driverSQLite {
+		query += ` FOR UPDATE`
+	}
+	return scanSolicitud(tx.QueryRowContext(ctx, rebind(query), id))
+}
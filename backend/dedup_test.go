@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// withDedup fija dedupWindow y dedupStrategy mientras dura el test.
+func withDedup(t *testing.T, window time.Duration, strategy string) {
+	t.Helper()
+	prevWindow, prevStrategy := dedupWindow, dedupStrategy
+	dedupWindow, dedupStrategy = window, strategy
+	t.Cleanup(func() { dedupWindow, dedupStrategy = prevWindow, prevStrategy })
+}
+
+// upsertInTx llama a upsertSolicitud en su propia transacción, como una
+// petición que no encontró el duplicado con findRecentDuplicate.
+func upsertInTx(t *testing.T, s Solicitud) (SolicitudGuardada, bool) {
+	t.Helper()
+	var (
+		saved    SolicitudGuardada
+		inserted bool
+	)
+	err := withTx(context.Background(), func(tx *sql.Tx) error {
+		id, ok, err := upsertSolicitud(context.Background(), tx, s, clientInfo{IP: "203.0.113.7"})
+		if err != nil {
+			return err
+		}
+		inserted = ok
+		saved, err = lockDuplicate(context.Background(), tx, id)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return saved, inserted
+}
+
+func countSolicitudes(t *testing.T) int {
+	t.Helper()
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM solicitudes`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// setFechaCreacion mueve la fecha de una solicitud para simular que se
+// creó hace ago.
+func setFechaCreacion(t *testing.T, id int64, ago time.Duration) {
+	t.Helper()
+	if _, err := db.Exec(`UPDATE solicitudes SET fecha_creacion = ? WHERE id = ?`, time.Now().UTC().Add(-ago).Format(time.DateTime), id); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fechaCreacion(t *testing.T, id int64) time.Time {
+	t.Helper()
+	var fecha dbTimestamp
+	if err := db.QueryRow(`SELECT fecha_creacion FROM solicitudes WHERE id = ?`, id).Scan(&fecha); err != nil {
+		t.Fatal(err)
+	}
+	return fecha.Time
+}
+
+func TestDedupStrategies(t *testing.T) {
+	for _, tc := range []struct {
+		strategy  string
+		wantCount int
+		wantMoved bool
+	}{
+		{dedupSkip, 1, false},
+		{dedupUpdate, 1, true},
+		{dedupCount, 2, false},
+	} {
+		t.Run(tc.strategy, func(t *testing.T) {
+			openTestDB(t)
+			withDedup(t, time.Minute, tc.strategy)
+			body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+
+			first := submit(t, body)
+			if first.Code != http.StatusCreated {
+				t.Fatalf("status = %d, body = %s", first.Code, first.Body)
+			}
+			id := responseID(t, first)
+			setFechaCreacion(t, id, 30*time.Second)
+			before := fechaCreacion(t, id)
+
+			second := submit(t, body)
+			if second.Code != http.StatusOK {
+				t.Fatalf("duplicado: status = %d, want 200 (body %s)", second.Code, second.Body)
+			}
+			if got := responseID(t, second); got != id {
+				t.Errorf("id del duplicado = %d, want %d", got, id)
+			}
+			if n := countSolicitudes(t); n != 1 {
+				t.Errorf("solicitudes guardadas = %d, want 1", n)
+			}
+
+			var count int
+			db.QueryRow(`SELECT submission_count FROM solicitudes WHERE id = ?`, id).Scan(&count)
+			if count != tc.wantCount {
+				t.Errorf("submission_count = %d, want %d", count, tc.wantCount)
+			}
+			if moved := fechaCreacion(t, id).After(before); moved != tc.wantMoved {
+				t.Errorf("fecha_creacion cambiada = %v, want %v", moved, tc.wantMoved)
+			}
+		})
+	}
+}
+
+// findRecentDuplicate y dedupKey deben coincidir en qué es un duplicado.
+func TestFindRecentDuplicateIgnoresCase(t *testing.T) {
+	openTestDB(t)
+	withDedup(t, time.Minute, dedupSkip)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"})
+
+	variant := Solicitud{Nombre: "ANA", Telefono: "+18095551111", Servicio: "mantenimiento de pc"}
+	if dedupKey(variant) != dedupKey(Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}) {
+		t.Fatal("dedupKey distingue mayúsculas")
+	}
+	dup, found, err := findRecentDuplicate(context.Background(), db, variant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || dup.ID != id {
+		t.Errorf("duplicado = %v, %d; want %d", found, dup.ID, id)
+	}
+}
+
+func TestDedupDisabled(t *testing.T) {
+	openTestDB(t)
+	withDedup(t, 0, dedupCount)
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+
+	for i := range 2 {
+		if rec := submit(t, body); rec.Code != http.StatusCreated {
+			t.Fatalf("envío %d: status = %d, want 201", i, rec.Code)
+		}
+	}
+	if n := countSolicitudes(t); n != 2 {
+		t.Errorf("solicitudes guardadas = %d, want 2", n)
+	}
+}
+
+// Dos envíos simultáneos no ven el duplicado con findRecentDuplicate; el
+// INSERT del segundo choca con el índice único y el upsert resuelve.
+func TestUpsertSolicitudConcurrentDuplicate(t *testing.T) {
+	for _, tc := range []struct {
+		strategy  string
+		wantCount int
+	}{
+		{dedupSkip, 1},
+		{dedupUpdate, 1},
+		{dedupCount, 2},
+	} {
+		t.Run(tc.strategy, func(t *testing.T) {
+			openTestDB(t)
+			withDedup(t, time.Minute, tc.strategy)
+			s := Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}
+
+			first, inserted := upsertInTx(t, s)
+			if !inserted {
+				t.Fatal("la primera solicitud no se insertó")
+			}
+			setFechaCreacion(t, first.ID, 30*time.Second)
+			before := fechaCreacion(t, first.ID)
+
+			// Igual salvo mayúsculas, como la compara MySQL
+			s.Nombre = "ANA"
+			second, inserted := upsertInTx(t, s)
+			if inserted {
+				t.Fatal("el duplicado se insertó como solicitud nueva")
+			}
+			if second.ID != first.ID || second.Nombre != "Ana" {
+				t.Errorf("duplicado = %d (%s), want %d (Ana)", second.ID, second.Nombre, first.ID)
+			}
+			if second.SubmissionCount != tc.wantCount {
+				t.Errorf("submission_count = %d, want %d", second.SubmissionCount, tc.wantCount)
+			}
+			if moved := fechaCreacion(t, first.ID).After(before); moved != (tc.strategy == dedupUpdate) {
+				t.Errorf("fecha_creacion cambiada = %v con %s", moved, tc.strategy)
+			}
+			if n := countSolicitudes(t); n != 1 {
+				t.Errorf("solicitudes guardadas = %d, want 1", n)
+			}
+		})
+	}
+}
+
+func TestUpsertSolicitudReleasesStaleKey(t *testing.T) {
+	openTestDB(t)
+	withDedup(t, time.Minute, dedupCount)
+	s := Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}
+
+	// Fuera de la ventana ya no es un duplicado
+	old, _ := upsertInTx(t, s)
+	setFechaCreacion(t, old.ID, 2*time.Minute)
+	recent, inserted := upsertInTx(t, s)
+	if !inserted || recent.ID == old.ID {
+		t.Fatalf("solicitud fuera de la ventana: inserted = %v, id = %d", inserted, recent.ID)
+	}
+
+	// Ni una eliminada
+	if _, err := db.Exec(`UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, recent.ID); err != nil {
+		t.Fatal(err)
+	}
+	if again, inserted := upsertInTx(t, s); !inserted || again.ID == recent.ID {
+		t.Fatalf("solicitud eliminada: inserted = %v, id = %d", inserted, again.ID)
+	}
+
+	var holders int
+	db.QueryRow(`SELECT COUNT(*) FROM solicitudes WHERE dedup_key IS NOT NULL`).Scan(&holders)
+	if holders != 1 {
+		t.Errorf("solicitudes con dedup_key = %d, want 1", holders)
+	}
+}
+
+func TestSubmitTwiceKeepsOneSolicitud(t *testing.T) {
+	openTestDB(t)
+	withDedup(t, defaultDedupWindow, dedupSkip)
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+
+	first := submit(t, body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", first.Code, first.Body)
+	}
+	second := submit(t, body)
+	if second.Code != http.StatusOK {
+		t.Fatalf("duplicado: status = %d, want 200 (body %s)", second.Code, second.Body)
+	}
+	if a, b := responseID(t, first), responseID(t, second); a != b {
+		t.Errorf("id del duplicado = %d, want %d", b, a)
+	}
+	if n := countSolicitudes(t); n != 1 {
+		t.Errorf("solicitudes guardadas = %d, want 1", n)
+	}
+
+	// Otro servicio, otro teléfono o una eliminada no son duplicados
+	if rec := submit(t, solicitudBody("Ana", "8095551111", "Recuperación de Datos")); rec.Code != http.StatusCreated {
+		t.Errorf("otro servicio: status = %d, want 201", rec.Code)
+	}
+	if rec := submit(t, solicitudBody("Ana", "8095552222", "Mantenimiento de PC")); rec.Code != http.StatusCreated {
+		t.Errorf("otro teléfono: status = %d, want 201", rec.Code)
+	}
+	db.Exec(`UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, responseID(t, first))
+	if rec := submit(t, body); rec.Code != http.StatusCreated {
+		t.Errorf("tras eliminar la original: status = %d, want 201", rec.Code)
+	}
+}
+
+// Tras editar una solicitud, volver a enviar los datos de antes crea otra.
+func TestSubmitOldValuesAfterUpdate(t *testing.T) {
+	openTestDB(t)
+	withDedup(t, defaultDedupWindow, dedupSkip)
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+
+	first := submit(t, body)
+	wantStatus(t, first, http.StatusCreated)
+	id := responseID(t, first)
+	wantStatus(t, serveAPI(t, "PUT", fmt.Sprintf("/v1/solicitudes/%d", id), solicitudBody("Ana María", "8095552222", "Recuperación de Datos")), http.StatusOK)
+
+	rec := submit(t, body)
+	wantStatus(t, rec, http.StatusCreated)
+	if got := responseID(t, rec); got == id {
+		t.Errorf("el envío devolvió la solicitud editada %d", id)
+	}
+	if n := countSolicitudes(t); n != 2 {
+		t.Errorf("solicitudes guardadas = %d, want 2", n)
+	}
+}
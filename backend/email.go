@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Puerto SMTP por defecto (envío con STARTTLS)
+const defaultSMTPPort = 587
+
+// smtpConfig son los datos del servidor de correo (SMTP_*).
+type smtpConfig struct {
+	Host     string
+	Port     int
+	User     string
+	Password string
+	From     string
+	To       []string
+}
+
+var emailTemplate = template.Must(template.New("email").Parse(`Se ha recibido una nueva solicitud de servicio.
+
+Número:   #{{.ID}}
+Nombre:   {{.Nombre}}
+Teléfono: {{.Telefono}}
+Servicio: {{.Servicio}}
+{{- if .Email}}
+Email:    {{.Email}}
+{{- end}}
+{{- if .HorarioPreferido}}
+Horario:  {{.HorarioPreferido}}
+{{- end}}
+Fecha:    {{.FechaCreacion}}
+{{- if .Mensaje}}
+
+Mensaje:
+{{.Mensaje}}
+{{- end}}
+`))
+
+// emailNotifier envía un correo a la oficina por cada solicitud nueva.
+type emailNotifier struct {
+	cfg smtpConfig
+	// send es sendMail; se puede sustituir para no enviar correos reales.
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func newEmailNotifier(cfg smtpConfig) *emailNotifier {
+	return &emailNotifier{cfg: cfg, send: sendMail}
+}
+
+func (n *emailNotifier) Name() string { return "email" }
+
+// Notify envía el correo. net/smtp no admite contexto, así que ctx solo se
+// comprueba antes de empezar; el envío tiene su propio plazo.
+func (n *emailNotifier) Notify(ctx context.Context, s SolicitudGuardada) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := n.message(s)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.cfg.User != "" {
+		auth = smtp.PlainAuth("", n.cfg.User, n.cfg.Password, n.cfg.Host)
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	return n.send(addr, auth, n.cfg.From, n.cfg.To, msg)
+}
+
+// message construye el correo con sus cabeceras. Los datos del cliente van
+// solo en el cuerpo para que no puedan inyectar cabeceras.
+func (n *emailNotifier) message(s SolicitudGuardada) ([]byte, error) {
+	var body bytes.Buffer
+	if err := emailTemplate.Execute(&body, s); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Nueva solicitud: "+s.Servicio))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// sendMail hace lo mismo que smtp.SendMail, pero con OUTBOUND_TIMEOUT como
+// plazo de toda la conversación: smtp.SendMail no tiene timeout y un
+// servidor que no responde dejaría la notificación colgada.
+func sendMail(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", addr, outboundTimeout)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(outboundTimeout))
+	host, _, _ := net.SplitHostPort(addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("el servidor SMTP no admite autenticación")
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envList lee una variable de entorno con valores separados por comas,
+// eliminando espacios y entradas vacías. Si la variable no está definida
+// o no contiene valores, devuelve def.
+func envList(name string, def []string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		return def
+	}
+	return values
+}
+
+// envInt lee una variable de entorno entera, devolviendo def si no está
+// definida. Si no es válida devuelve def junto con el error, para que
+// LoadConfig pueda seguir comprobando el resto.
+func envInt(name string, def int) (int, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return def, fmt.Errorf("%s debe ser un número entero: %q", name, raw)
+	}
+	return n, nil
+}
+
+// envFloat lee una variable de entorno decimal, devolviendo def si no está
+// definida.
+func envFloat(name string, def float64) (float64, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return def, fmt.Errorf("%s debe ser un número: %q", name, raw)
+	}
+	return f, nil
+}
+
+// envBool lee una variable de entorno booleana (true/false, 1/0),
+// devolviendo def si no está definida.
+func envBool(name string, def bool) (bool, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return def, fmt.Errorf("%s debe ser true o false: %q", name, raw)
+	}
+	return b, nil
+}
+
+// envDuration lee una variable de entorno con formato de duración de Go
+// (por ejemplo "5m" o "30s"), devolviendo def si no está definida.
+func envDuration(name string, def time.Duration) (time.Duration, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return def, fmt.Errorf("%s debe ser una duración como \"30s\" o \"5m\": %q", name, raw)
+	}
+	return d, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Códigos de error de la API. El cliente debe basarse en el código (y en el
+// estado HTTP), no en el texto de message, que puede cambiar.
+const (
+	codeInvalidJSON          = "invalid_json"           // 400: el cuerpo no es JSON válido
+	codeValidation           = "validation_error"       // 400: un campo no es válido; ver field
+	codeInvalidParameter     = "invalid_parameter"      // 400: parámetro de ruta o query inválido
+	codeCaptchaFailed        = "captcha_failed"         // 400: el captcha falta o no se pudo verificar
+	codeUnauthorized         = "unauthorized"           // 401: falta la clave de administración
+	codeForbidden            = "forbidden"              // 403: la clave de administración no es válida
+	codeNotFound             = "not_found"              // 404: el recurso no existe
+	codeMethodNotAllowed     = "method_not_allowed"     // 405: método HTTP no soportado en la ruta
+	codeIdempotencyConflict  = "idempotency_conflict"   // 409: Idempotency-Key reutilizada con otro cuerpo o aún en curso
+	codePayloadTooLarge      = "payload_too_large"      // 413: el cuerpo supera MAX_BODY_BYTES
+	codeUnsupportedMediaType = "unsupported_media_type" // 415: el cuerpo no es application/json
+	codeRateLimited          = "rate_limited"           // 429: demasiadas peticiones; ver Retry-After
+	codeQuotaExceeded        = "quota_exceeded"         // 429: cupo diario del servicio agotado; ver Retry-After
+	codeInternal             = "internal_error"         // 500: error inesperado del servidor
+	codeServiceUnavailable   = "service_unavailable"    // 503: el servicio no está listo
+	codeDBTimeout            = "db_timeout"             // 504: la base de datos no respondió a tiempo
+)
+
+// APIError es el cuerpo JSON de todas las respuestas de error. En los de
+// validación, Errors lista todos los campos inválidos y Field es el primero.
+type APIError struct {
+	Message   string           `json:"message"`
+	Code      string           `json:"code,omitempty"`
+	Field     string           `json:"field,omitempty"`
+	Errors    validationErrors `json:"errors,omitempty"`
+	RequestID string           `json:"request_id,omitempty"`
+}
+
+// writeError responde con un APIError con el código y mensaje indicados.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeAPIError(w, status, APIError{Message: msg, Code: code})
+}
+
+// writeAPIError escribe apiErr como JSON, con los mensajes traducidos al
+// idioma de la respuesta y completando el request_id de la petición para que
+// soporte pueda pedírselo al usuario.
+func writeAPIError(w http.ResponseWriter, status int, apiErr APIError) {
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = w.Header().Get(requestIDHeader)
+	}
+	lang := responseLang(w)
+	apiErr.Message = translate(lang, apiErr.Message)
+	if len(apiErr.Errors) > 0 {
+		errs := make(validationErrors, len(apiErr.Errors))
+		for i, e := range apiErr.Errors {
+			errs[i] = &validationError{Field: e.Field, Message: translate(lang, e.Message)}
+		}
+		apiErr.Errors = errs
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiErr)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// listingVersion resume el estado de las solicitudes que ve un listado o
+// una estadística, para saber sin leer las filas si la respuesta cambió.
+// Las altas cambian Count y MaxID, las solicitudes repetidas
+// SubmissionTotal o la fecha, y las ediciones, borrados y restauraciones
+// dejan una entrada en audit_log.
+type listingVersion struct {
+	Count           int64
+	MaxID           int64
+	SubmissionTotal int64
+	AuditID         int64
+	// LastModified es la fecha más reciente entre las solicitudes y la
+	// auditoría; cero si no hay ninguna
+	LastModified time.Time
+	// filter son los argumentos del filtro, que pueden depender del día
+	// (el rango por defecto de /stats/daily) aunque la query no cambie
+	filter string
+}
+
+// loadListingVersion calcula la versión de las solicitudes de filtro con
+// dos consultas de agregados, mucho más baratas que el listado.
+func loadListingVersion(ctx context.Context, filtro filtroSolicitudes) (listingVersion, error) {
+	v := listingVersion{filter: fmt.Sprint(filtro.args...)}
+	var lastCreated, lastAudit dbTimestamp
+	err := db.QueryRowContext(ctx, rebind(`SELECT COUNT(*), COALESCE(MAX(id), 0), COALESCE(SUM(submission_count), 0), MAX(fecha_creacion) FROM solicitudes`+
+		filtro.where()), filtro.args...).Scan(&v.Count, &v.MaxID, &v.SubmissionTotal, &lastCreated)
+	if err != nil {
+		return v, err
+	}
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0), MAX(created_at) FROM audit_log`).Scan(&v.AuditID, &lastAudit)
+	if err != nil {
+		return v, err
+	}
+	v.LastModified = lastCreated.Time
+	if lastAudit.Valid && lastAudit.Time.After(v.LastModified) {
+		v.LastModified = lastAudit.Time
+	}
+	return v, nil
+}
+
+// etag devuelve la ETag de la respuesta a r con esta versión. Incluye la
+// query, para que cada página o filtro tenga la suya, y el formato de las
+// respuestas. Es débil porque la compresión cambia los bytes.
+func (v listingVersion) etag(r *http.Request) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%d/%d/%d/%d/%d", r.URL.Path, r.URL.Query().Encode(), v.filter, emptyFieldsPolicy,
+		v.Count, v.MaxID, v.SubmissionTotal, v.AuditID, v.LastModified.Unix())
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// writeIfNotModified carga la versión de filtro y llama a checkNotModified.
+// Devuelve true si ya se respondió, con 304 o con el error de la base de
+// datos (msg).
+func writeIfNotModified(ctx context.Context, w http.ResponseWriter, r *http.Request, filtro filtroSolicitudes, msg string) (listingVersion, bool) {
+	version, err := loadListingVersion(ctx, filtro)
+	if err != nil {
+		writeDBError(w, r, err, msg)
+		return version, true
+	}
+	return version, checkNotModified(w, r, version)
+}
+
+// checkNotModified pone ETag y Last-Modified y, si el cliente envía en
+// If-None-Match la ETag de esta versión, responde 304 y devuelve true.
+// If-Modified-Since no se atiende: hay cambios que no mueven ninguna fecha
+// (las solicitudes repetidas, el reprocesado del respaldo con su fecha de
+// recepción, la purga), así que daría 304 con datos viejos.
+func checkNotModified(w http.ResponseWriter, r *http.Request, v listingVersion) bool {
+	etag := v.etag(r)
+	h := w.Header()
+	h.Set("ETag", etag)
+	// Los paneles deben revalidar siempre, no usar la copia sin preguntar
+	h.Set("Cache-Control", "private, no-cache")
+	if !v.LastModified.IsZero() {
+		h.Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	inm := r.Header.Get("If-None-Match")
+	if inm == "" || !etagMatches(inm, etag) {
+		return false
+	}
+	h.Del("Content-Type")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches compara con la comparación débil de If-None-Match: da igual
+// el prefijo W/, y "*" coincide con cualquiera.
+func etagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
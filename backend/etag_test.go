@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getWithETag hace GET de target con h y, si etag no está vacío, con
+// If-None-Match.
+func getWithETag(h http.HandlerFunc, target, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec
+}
+
+func TestListingNotModified(t *testing.T) {
+	openTestDB(t)
+	seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+
+	endpoints := []struct {
+		handler http.HandlerFunc
+		target  string
+	}{
+		{solicitudesHandler, "/v1/solicitudes?limit=10"},
+		{solicitudesHandler, "/v1/solicitudes?after=&limit=10"},
+		{solicitudesCountHandler, "/v1/solicitudes/count"},
+		{statsByServiceHandler, "/v1/stats/by-service"},
+		{statsDailyHandler, "/v1/stats/daily"},
+	}
+	for _, e := range endpoints {
+		first := getWithETag(e.handler, e.target, "")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: status = %d, ETag = %q", e.target, first.Code, etag)
+		}
+
+		again := getWithETag(e.handler, e.target, etag)
+		if again.Code != http.StatusNotModified {
+			t.Errorf("%s: status = %d, want 304", e.target, again.Code)
+		}
+		if again.Body.Len() != 0 {
+			t.Errorf("%s: el 304 lleva cuerpo: %q", e.target, again.Body)
+		}
+
+		// Otra ETag (otra página, otro filtro) no coincide
+		if rec := getWithETag(e.handler, e.target, `W/"otra"`); rec.Code != http.StatusOK {
+			t.Errorf("%s con otra ETag: status = %d, want 200", e.target, rec.Code)
+		}
+	}
+}
+
+func TestListingETagChangesAfterEdit(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+
+	first := getWithETag(solicitudesHandler, "/v1/solicitudes", "")
+	etag := first.Header().Get("ETag")
+
+	// Cambiar el estado no cambia el número de filas ni el id máximo
+	req := httptest.NewRequest("PATCH", "/v1/solicitudes/1/status", strings.NewReader(`{"status": "contactado"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	updateStatusHandler(rec, req, id)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cambio de estado: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	after := getWithETag(solicitudesHandler, "/v1/solicitudes", etag)
+	if after.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 tras editar", after.Code)
+	}
+	if !strings.Contains(after.Body.String(), `"contactado"`) {
+		t.Errorf("la respuesta no trae el cambio: %s", after.Body)
+	}
+	if after.Header().Get("ETag") == etag {
+		t.Error("la ETag no cambió tras editar")
+	}
+}
+
+func TestListingETagChangesOnRepeatedSubmission(t *testing.T) {
+	openTestDB(t)
+	prevWindow, prevStrategy := dedupWindow, dedupStrategy
+	dedupWindow, dedupStrategy = time.Hour, dedupCount
+	t.Cleanup(func() { dedupWindow, dedupStrategy = prevWindow, prevStrategy })
+
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+	if rec := submit(t, body); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	first := getWithETag(solicitudesHandler, "/v1/solicitudes", "")
+	etag, lastModified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+
+	// Con DEDUP_STRATEGY=count solo sube submission_count, sin mover fechas
+	if rec := submit(t, body); rec.Code != http.StatusOK {
+		t.Fatalf("duplicado: status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	if rec := getWithETag(solicitudesHandler, "/v1/solicitudes", etag); rec.Code != http.StatusOK {
+		t.Errorf("If-None-Match: status = %d, want 200", rec.Code)
+	}
+	req := httptest.NewRequest("GET", "/v1/solicitudes", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rec := httptest.NewRecorder()
+	solicitudesHandler(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("If-Modified-Since: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	for header, want := range map[string]bool{
+		`W/"abc"`:      true,
+		`"abc"`:        true,
+		`"x", W/"abc"`: true,
+		`*`:            true,
+		`W/"abd"`:      false,
+		`"x",  "y"`:    false,
+		`W/"abc-gzip"`: false,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+}
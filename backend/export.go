@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// solicitudesCSVHandler exporta las solicitudes como CSV, con los mismos
+// filtros que GET /solicitudes (servicio, from, to) pero sin paginar. Las
+// filas se escriben según llegan de la base de datos, sin acumularlas.
+func solicitudesCSVHandler(w http.ResponseWriter, r *http.Request) {
+	filtro, err := parseFiltroSolicitudes(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes`+filtro.where()+
+		` ORDER BY fecha_creacion DESC, id DESC`), filtro.args...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al exportar las solicitudes")
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="solicitudes.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "nombre", "telefono", "servicio", "email", "mensaje", "horario_preferido", "status", "assigned_to", "fecha_creacion", "ip_address", "user_agent", "submission_count"})
+	for rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			// La cabecera ya se envió: solo queda registrar el error y cortar.
+			requestLogger(r).Error("Error al leer una solicitud durante la exportación", "error", err)
+			return
+		}
+		var assignedTo string
+		if s.AssignedTo != nil {
+			assignedTo = *s.AssignedTo
+		}
+		out.Write([]string{strconv.FormatInt(s.ID, 10), csvCell(s.Nombre), s.Telefono, csvCell(s.Servicio), csvCell(s.Email),
+			csvCell(s.Mensaje), s.HorarioPreferido, s.Status, csvCell(assignedTo), s.FechaCreacion, s.IPAddress, csvCell(s.UserAgent),
+			strconv.Itoa(s.SubmissionCount)})
+	}
+	out.Flush()
+	if err := rows.Err(); err != nil {
+		requestLogger(r).Error("Error al recorrer las solicitudes durante la exportación", "error", err)
+		return
+	}
+	if err := out.Error(); err != nil {
+		requestLogger(r).Warn("Error al escribir el CSV", "error", err)
+	}
+}
+
+// csvCell protege un texto escrito por el usuario para abrir el CSV en Excel
+// o en Sheets: si empieza por un carácter que lo haría fórmula, se le
+// antepone una comilla simple para que se muestre como texto.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
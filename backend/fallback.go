@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Cada cuánto se intenta pasar a la base de datos lo guardado en el fichero
+// de respaldo (FALLBACK_REPLAY_INTERVAL)
+const defaultFallbackReplayInterval = 30 * time.Second
+
+// fallbackConfig configura el respaldo en fichero: si la base de datos no
+// está accesible al guardar una solicitud, se anota en Path (JSONL) y se
+// reintenta cada ReplayInterval. Path vacío lo desactiva.
+type fallbackConfig struct {
+	Path           string
+	ReplayInterval time.Duration
+}
+
+// spooledSolicitud es una línea del fichero de respaldo.
+type spooledSolicitud struct {
+	Solicitud
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// fallbackStore guarda en un fichero de solo añadir las solicitudes que no
+// se pudieron insertar y las pasa a la base de datos desde una goroutine
+// cuando vuelve a estar accesible.
+//
+// Para reprocesar, el fichero se renombra a Path+".replay" y las nuevas
+// solicitudes empiezan otro. Si el proceso muere a mitad, el .replay se
+// retoma al arrancar; las solicitudes ya insertadas de ese fichero se
+// vuelven a insertar, así que puede haber alguna repetida.
+type fallbackStore struct {
+	path     string
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	// mu serializa las escrituras en path y su renombrado
+	mu sync.Mutex
+}
+
+// fallbackSpool es el respaldo en fichero, o nil si FALLBACK_FILE no está
+// configurada.
+var fallbackSpool *fallbackStore
+
+// startFallbackStore lanza el reprocesado: una pasada al arrancar, por si
+// quedó algo de la ejecución anterior, y otra cada ReplayInterval.
+func startFallbackStore(cfg fallbackConfig) *fallbackStore {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &fallbackStore{path: cfg.Path, interval: cfg.ReplayInterval, cancel: cancel, done: make(chan struct{})}
+	go s.run(ctx)
+	return s
+}
+
+// Append añade las solicitudes al fichero y espera a que estén en disco.
+func (s *fallbackStore) Append(batch []pendingSolicitud) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, p := range batch {
+		if err := enc.Encode(spooledSolicitud{Solicitud: p.Solicitud, IP: p.Client.IP, UserAgent: p.Client.UserAgent, ReceivedAt: now}); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return appendSynced(s.path, buf.Bytes())
+}
+
+// appendSynced añade data al final de path, creándolo si no existe, y
+// espera a que esté en disco.
+func appendSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// appendSpooled añade una línea con e a path.
+func appendSpooled(path string, e spooledSolicitud) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return appendSynced(path, append(data, '\n'))
+}
+
+func (s *fallbackStore) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.replay(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop detiene el reprocesado y espera a que termine la pasada en curso, o
+// a que venza ctx. Lo que quede en el fichero se retoma al arrancar.
+func (s *fallbackStore) Stop(ctx context.Context) {
+	s.cancel()
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		logger.Warn("Apagado con el reprocesado del respaldo en curso")
+	}
+}
+
+// replay pasa a la base de datos las solicitudes del fichero de respaldo.
+// Primero termina el .replay que quede de una pasada anterior y, si lo
+// consigue, toma el fichero actual.
+func (s *fallbackStore) replay(ctx context.Context) {
+	replayPath := s.path + ".replay"
+	for {
+		if _, err := os.Stat(replayPath); errors.Is(err, os.ErrNotExist) {
+			taken, err := s.take(replayPath)
+			if err != nil {
+				logger.Error("Error al preparar el reprocesado del respaldo", "path", s.path, "error", err)
+				return
+			}
+			if !taken {
+				return
+			}
+		}
+		if !s.replayFile(ctx, replayPath) {
+			return
+		}
+	}
+}
+
+// take renombra el fichero de respaldo a replayPath si tiene algo. Devuelve
+// false si no hay nada que reprocesar.
+func (s *fallbackStore) take(replayPath string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil || info.Size() == 0 {
+		return false, err
+	}
+	return true, os.Rename(s.path, replayPath)
+}
+
+// replayFile inserta una a una las solicitudes de path. Si la base de datos
+// sigue sin estar accesible, reescribe path con las que faltan y devuelve
+// false para reintentarlo en la siguiente pasada.
+func (s *fallbackStore) replayFile(ctx context.Context, path string) bool {
+	entries, err := readSpooled(path)
+	if err != nil {
+		logger.Error("Error al leer el fichero de respaldo", "path", path, "error", err)
+		return false
+	}
+
+	inserted := 0
+	for i, e := range entries {
+		if ctx.Err() != nil {
+			return s.keepPending(path, entries[i:], inserted)
+		}
+		err := insertSpooled(ctx, e)
+		if err != nil && isDBUnavailableError(err) {
+			return s.keepPending(path, entries[i:], inserted)
+		}
+		if err != nil {
+			// No se puede reintentar para siempre: se aparta en .failed para
+			// recuperarla a mano. El log no lleva los datos personales
+			if failErr := appendSpooled(s.failedPath(), e); failErr != nil {
+				logger.Error("Error al apartar una solicitud del respaldo no guardada", "path", s.failedPath(), "error", failErr)
+			}
+			logger.Error("Solicitud del respaldo no guardada, apartada para revisarla a mano",
+				"path", s.failedPath(), "entry", i+1, "servicio", e.Servicio, "received_at", formatTimestamp(e.ReceivedAt), "error", err)
+			continue
+		}
+		inserted++
+	}
+
+	if err := os.Remove(path); err != nil {
+		logger.Error("Error al borrar el fichero de respaldo reprocesado", "path", path, "error", err)
+		return false
+	}
+	if inserted > 0 {
+		logger.Info("Solicitudes del respaldo guardadas", "count", inserted)
+		responseCache.invalidate()
+	}
+	return true
+}
+
+// failedPath es el fichero donde se apartan las solicitudes que la base de
+// datos rechazó, para recuperarlas a mano.
+func (s *fallbackStore) failedPath() string {
+	return s.path + ".failed"
+}
+
+// setAside aparta en failedPath un lote que la base de datos rechazó.
+func (s *fallbackStore) setAside(batch []pendingSolicitud) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, p := range batch {
+		e := spooledSolicitud{Solicitud: p.Solicitud, IP: p.Client.IP, UserAgent: p.Client.UserAgent, ReceivedAt: now}
+		if err := appendSpooled(s.failedPath(), e); err != nil {
+			logger.Error("Error al apartar una solicitud no guardada", "path", s.failedPath(), "error", err)
+			return
+		}
+	}
+	logger.Warn("Solicitudes no guardadas apartadas para revisarlas a mano", "path", s.failedPath(), "count", len(batch))
+}
+
+// keepPending reescribe path con las solicitudes que faltan por guardar.
+func (s *fallbackStore) keepPending(path string, pending []spooledSolicitud, inserted int) bool {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range pending {
+		enc.Encode(e)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		logger.Error("Error al reescribir el fichero de respaldo", "path", path, "error", err)
+	} else if err := os.Rename(tmp, path); err != nil {
+		logger.Error("Error al reescribir el fichero de respaldo", "path", path, "error", err)
+	}
+	logger.Warn("La base de datos sigue sin estar accesible, se reintentará el respaldo",
+		"inserted", inserted, "pending", len(pending), "retry_in", s.interval.String())
+	if inserted > 0 {
+		responseCache.invalidate()
+	}
+	return false
+}
+
+// readSpooled lee las solicitudes de un fichero de respaldo. Las líneas que
+// no son JSON válido (una escritura cortada por un apagón) se registran y
+// se saltan.
+func readSpooled(path string) ([]spooledSolicitud, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []spooledSolicitud
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), int(maxBodyBytes)+64*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e spooledSolicitud
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			logger.Error("Línea inválida en el fichero de respaldo", "path", path, "line", line, "error", err)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// insertSpooled guarda una solicitud del respaldo con la fecha en que se
+// recibió, y la notifica como cualquier solicitud nueva.
+func insertSpooled(ctx context.Context, e spooledSolicitud) error {
+	ctx, cancel := context.WithTimeout(ctx, dbQueryTimeout)
+	defer cancel()
+
+	var id int64
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if id, err = insertSolicitud(ctx, tx, e.Solicitud, clientInfo{IP: e.IP, UserAgent: e.UserAgent}); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, rebind(`UPDATE solicitudes SET fecha_creacion = ? WHERE id = ?`), e.ReceivedAt, id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	recordSolicitudCreada(e.Servicio)
+	creada, err := findSolicitud(ctx, id)
+	if err != nil {
+		// Ya está guardada: solo se pierde el aviso
+		logger.Error("Error al consultar la solicitud del respaldo para avisar", "id", id, "error", err)
+		return nil
+	}
+	notifyNuevaSolicitud(logger, creada)
+	return nil
+}
+
+// isDBUnavailableError indica si err se debe a que no se puede conectar
+// con la base de datos, no a la consulta. Los timeouts no cuentan: la
+// solicitud pudo llegar a guardarse.
+func isDBUnavailableError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return isBadConnError(err) || errors.As(err, &netErr) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// acceptSpooled guarda la solicitud en el respaldo y responde 202. Si
+// tampoco se puede escribir el fichero, responde como writeDBError.
+func acceptSpooled(w http.ResponseWriter, r *http.Request, s Solicitud, dbErr error) {
+	if err := fallbackSpool.Append([]pendingSolicitud{{Solicitud: s, Client: requestClientInfo(r)}}); err != nil {
+		requestLogger(r).Error("Error al escribir en el fichero de respaldo", "error", err)
+		writeDBError(w, r, dbErr, "Error interno del servidor al guardar la solicitud")
+		return
+	}
+	requestLogger(r).Warn("Base de datos no accesible, solicitud guardada en el respaldo", "servicio", s.Servicio, "error", dbErr)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": translate(responseLang(w), "Solicitud recibida")})
+}
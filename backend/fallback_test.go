@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// withFallbackSpool activa el respaldo en un fichero temporal mientras dura
+// el test.
+func withFallbackSpool(t *testing.T) *fallbackStore {
+	t.Helper()
+	s := &fallbackStore{path: filepath.Join(t.TempDir(), "pendientes.jsonl"), interval: time.Minute}
+	prev := fallbackSpool
+	fallbackSpool = s
+	t.Cleanup(func() { fallbackSpool = prev })
+	return s
+}
+
+// writeSpooled escribe entries en path como lo haría Append.
+func writeSpooled(t *testing.T, path string, entries ...spooledSolicitud) {
+	t.Helper()
+	for _, e := range entries {
+		if err := appendSpooled(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSubmitSpoolsWhenDBUnavailable(t *testing.T) {
+	useUnreachableDB(t)
+	s := withFallbackSpool(t)
+
+	rec := submit(t, solicitudBody("Ana Pérez", "8095551111", "Mantenimiento de PC"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202 (body %s)", rec.Code, rec.Body)
+	}
+	var body map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &body)
+	if body["message"] != "Solicitud recibida" {
+		t.Errorf("body = %v", body)
+	}
+
+	entries, err := readSpooled(s.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Nombre != "Ana Pérez" || entries[0].Servicio != "Mantenimiento de PC" {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if entries[0].IP == "" || entries[0].ReceivedAt.IsZero() {
+		t.Errorf("faltan los datos del cliente o la fecha: %+v", entries[0])
+	}
+}
+
+func TestSubmitWithoutFallbackFailsWhenDBUnavailable(t *testing.T) {
+	useUnreachableDB(t)
+	if rec := submit(t, solicitudBody("Ana", "8095551111", "Mantenimiento de PC")); rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+func TestFallbackReplayInsertsWithReceivedAt(t *testing.T) {
+	openTestDB(t)
+	s := withFallbackSpool(t)
+	received := time.Date(2026, 3, 1, 10, 30, 0, 0, time.UTC)
+	writeSpooled(t, s.path,
+		spooledSolicitud{Solicitud: Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}, IP: "203.0.113.7", ReceivedAt: received},
+		spooledSolicitud{Solicitud: Solicitud{Nombre: "Luis", Telefono: "+18095552222", Servicio: "Recuperación de Datos"}, ReceivedAt: received.Add(time.Minute)},
+	)
+
+	s.replay(context.Background())
+
+	for _, p := range []string{s.path, s.path + ".replay"} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s sigue existiendo tras reprocesar", filepath.Base(p))
+		}
+	}
+	ana, err := findSolicitud(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ana.Nombre != "Ana" || ana.IPAddress != "203.0.113.7" || ana.FechaCreacion != "2026-03-01T10:30:00Z" {
+		t.Errorf("solicitud guardada = %+v", ana)
+	}
+	if _, err := findSolicitud(context.Background(), 2); err != nil {
+		t.Errorf("la segunda solicitud no se guardó: %v", err)
+	}
+}
+
+func TestFallbackReplayKeepsPendingWhileDBIsDown(t *testing.T) {
+	useUnreachableDB(t)
+	s := withFallbackSpool(t)
+	writeSpooled(t, s.path,
+		spooledSolicitud{Solicitud: Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}, ReceivedAt: time.Now().UTC()},
+		spooledSolicitud{Solicitud: Solicitud{Nombre: "Luis", Telefono: "+18095552222", Servicio: "Mantenimiento de PC"}, ReceivedAt: time.Now().UTC()},
+	)
+
+	s.replay(context.Background())
+
+	// Se retoman en la siguiente pasada desde .replay, sin perder ninguna
+	entries, err := readSpooled(s.path + ".replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Nombre != "Ana" || entries[1].Nombre != "Luis" {
+		t.Errorf("pendientes = %+v", entries)
+	}
+
+	// Lo que llega mientras tanto va a un fichero nuevo
+	if err := s.Append([]pendingSolicitud{{Solicitud: Solicitud{Nombre: "Eva", Telefono: "+18095553333", Servicio: "Mantenimiento de PC"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := readSpooled(s.path); len(entries) != 1 {
+		t.Errorf("nuevo fichero = %+v", entries)
+	}
+}
+
+func TestFallbackReplaySetsAsideRejected(t *testing.T) {
+	conn := openTestDB(t)
+	s := withFallbackSpool(t)
+	writeSpooled(t, s.path, spooledSolicitud{Solicitud: Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}, ReceivedAt: time.Now().UTC()})
+	// Un error que no es de conexión: reintentarlo no serviría de nada
+	if _, err := conn.Exec(`DROP TABLE solicitudes`); err != nil {
+		t.Fatal(err)
+	}
+
+	s.replay(context.Background())
+
+	failed, err := readSpooled(s.path + ".failed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Nombre != "Ana" {
+		t.Errorf("apartadas = %+v", failed)
+	}
+	if _, err := os.Stat(s.path + ".replay"); !os.IsNotExist(err) {
+		t.Error("el .replay no se borró")
+	}
+}
+
+func TestReadSpooledSkipsTornLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pendientes.jsonl")
+	content := `{"nombre":"Ana","telefono":"+18095551111","servicio":"Mantenimiento de PC","received_at":"2026-03-01T10:30:00Z"}` + "\n\n" +
+		`{"nombre":"Lu` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := readSpooled(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Nombre != "Ana" {
+		t.Errorf("entries = %+v", entries)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Tiempo máximo para el ping a la base de datos en los health checks
+const healthPingTimeout = 2 * time.Second
+
+// healthHandler responde 200 si la base de datos responde al ping y 503 si
+// no (o si todavía no se ha conectado). Sirve tanto /health como /readyz.
+// Solo se registran los fallos para no llenar los logs con cada chequeo.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if !dbReady.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthPingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		requestLogger(r).Warn("Health check fallido", "error", err, "status", http.StatusServiceUnavailable)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unhealthy"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// livezHandler indica solo que el proceso está vivo; no consulta la base
+// de datos.
+func livezHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// pingHandler responde "pong" sin consultar nada, para los monitores de
+// disponibilidad que llaman con mucha frecuencia.
+func pingHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, "pong")
+}
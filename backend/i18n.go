@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Idiomas de los mensajes de la API. Los mensajes se escriben en español en
+// el código; el cliente pide otro idioma con ?lang= o Accept-Language.
+const (
+	langES      = "es"
+	langEN      = "en"
+	defaultLang = langES
+)
+
+// messageCatalogs traduce los mensajes del español a los demás idiomas. Las
+// claves pueden llevar %s o %d para los mensajes con datos; en la traducción
+// los datos se insertan siempre como texto (%s, o %[n]s para cambiar el
+// orden).
+var messageCatalogs = map[string]map[string]string{
+	langEN: {
+		"Bienvenido a la API de servicios. Usa /v1/submit-service para enviar datos.": "Welcome to the services API. Use /v1/submit-service to send data.",
+
+		"Solicitud recibida": "Request received",
+		"Eliminada":          "Deleted",
+
+		"Ruta no encontrada":                "Route not found",
+		"Método no permitido":               "Method not allowed",
+		"Solicitud no encontrada":           "Request not found",
+		"Solicitud eliminada no encontrada": "Deleted request not found",
+
+		"Validación fallida":                                          "Validation failed",
+		"El campo '%s' es obligatorio":                                "The '%s' field is required",
+		"El campo '%s' no puede superar %d caracteres":                "The '%s' field cannot exceed %s characters",
+		"El campo '%s' debe ser texto":                                "The '%s' field must be a string",
+		"Teléfono inválido":                                           "Invalid phone number",
+		"Servicio no reconocido":                                      "Unknown service",
+		"El campo 'horario_preferido' debe ser mañana, tarde o noche": "The 'horario_preferido' field must be mañana, tarde or noche",
+		"Correo electrónico inválido":                                 "Invalid email address",
+		"Campo desconocido: '%s'":                                     "Unknown field: '%s'",
+		"Estado no válido: debe ser nuevo, contactado o cerrado":      "Invalid status: must be nuevo, contactado or cerrado",
+		"No se puede pasar de '%s' a '%s'":                            "Cannot change from '%s' to '%s'",
+		"El campo 'assigned_to' debe tener como mucho %d caracteres y no puede ser '%s'": "The 'assigned_to' field must have at most %s characters and cannot be '%s'",
+
+		"El cuerpo debe enviarse como application/json":                         "The body must be sent as application/json",
+		"El cuerpo no puede superar %d bytes":                                   "The body cannot exceed %s bytes",
+		"El cuerpo de la solicitud está vacío":                                  "The request body is empty",
+		"El cuerpo solo puede contener un objeto JSON":                          "The body can only contain one JSON object",
+		"El cuerpo debe ser un objeto JSON":                                     "The body must be a JSON object",
+		"El cuerpo debe ser un array JSON de solicitudes":                       "The body must be a JSON array of requests",
+		"Cada elemento debe ser un objeto JSON con nombre, telefono y servicio": "Each element must be a JSON object with nombre, telefono and servicio",
+		"No se pueden importar más de %d solicitudes a la vez":                  "Cannot import more than %s requests at once",
+		"El JSON está incompleto":                                               "The JSON is incomplete",
+		"JSON mal formado en la posición %d":                                    "Malformed JSON at position %s",
+		"Error al decodificar la solicitud JSON":                                "Could not decode the JSON request",
+
+		"El id de la solicitud debe ser un entero positivo":            "The request id must be a positive integer",
+		"El parámetro '%s' debe ser un entero no negativo":             "The '%s' parameter must be a non-negative integer",
+		"El parámetro '%s' es obligatorio":                             "The '%s' parameter is required",
+		"El parámetro '%s' debe ser true o false":                      "The '%s' parameter must be true or false",
+		"El parámetro '%s' debe tener el formato YYYY-MM-DD":           "The '%s' parameter must use the YYYY-MM-DD format",
+		"El parámetro '%s' no puede superar %d caracteres":             "The '%s' parameter cannot exceed %s characters",
+		"El parámetro 'after' debe ser un id positivo o estar vacío":   "The 'after' parameter must be a positive id or empty",
+		"Los parámetros 'after' y 'offset' no se pueden usar a la vez": "The 'after' and 'offset' parameters cannot be used together",
+		"El parámetro 'from' debe ser anterior a 'to'":                 "The 'from' parameter must be before 'to'",
+		"El parámetro 'status' debe ser nuevo, contactado o cerrado":   "The 'status' parameter must be nuevo, contactado or cerrado",
+		"El rango no puede superar %d días":                            "The range cannot exceed %s days",
+
+		"Se requiere autenticación":  "Authentication required",
+		"Clave de acceso incorrecta": "Invalid access key",
+		"Demasiadas solicitudes":     "Too many requests",
+
+		"No se pudo verificar que no eres un robot. Recarga la página e inténtalo de nuevo.": "Could not verify that you are not a robot. Reload the page and try again.",
+		"No se pudo verificar el captcha, inténtalo de nuevo en unos segundos":               "Could not verify the captcha, try again in a few seconds",
+
+		"La cabecera Idempotency-Key no puede superar 255 caracteres":                                       "The Idempotency-Key header cannot exceed 255 characters",
+		"La cabecera Idempotency-Key ya se usó con una solicitud distinta":                                  "The Idempotency-Key header was already used with a different request",
+		"Ya se está procesando una solicitud con esta Idempotency-Key, inténtalo de nuevo en unos segundos": "A request with this Idempotency-Key is already being processed, try again in a few seconds",
+
+		"El servicio se está iniciando, inténtalo de nuevo en unos segundos":              "The service is starting, try again in a few seconds",
+		"Hay demasiadas solicitudes en este momento, inténtalo de nuevo en unos segundos": "There are too many requests right now, try again in a few seconds",
+		"La petición tardó demasiado, inténtalo de nuevo":                                 "The request took too long, try again",
+		"En mantenimiento": "Under maintenance",
+		"El campo 'servicio' debe tener entre %d y %d caracteres":     "The 'servicio' field must be between %s and %s characters long",
+		"El campo 'servicio' contiene caracteres no permitidos":       "The 'servicio' field contains characters that are not allowed",
+		"Cupo diario alcanzado para este servicio":                    "Daily quota reached for this service",
+		"Servicio temporalmente no disponible":                        "Service temporarily unavailable",
+		"La base de datos tardó demasiado en responder":               "The database took too long to respond",
+		"Error interno del servidor":                                  "Internal server error",
+		"Error interno del servidor al guardar la solicitud":          "Internal server error while saving the request",
+		"Error interno del servidor al consultar la solicitud creada": "Internal server error while reading the created request",
+		"Error interno del servidor al comprobar la Idempotency-Key":  "Internal server error while checking the Idempotency-Key",
+		"Error interno del servidor al consultar la solicitud":        "Internal server error while reading the request",
+		"Error interno del servidor al consultar las solicitudes":     "Internal server error while reading the requests",
+		"Error interno del servidor al contar las solicitudes":        "Internal server error while counting the requests",
+		"Error interno del servidor al exportar las solicitudes":      "Internal server error while exporting the requests",
+		"Error interno del servidor al importar las solicitudes":      "Internal server error while importing the requests",
+		"Error interno del servidor al actualizar la solicitud":       "Internal server error while updating the request",
+		"Error interno del servidor al eliminar la solicitud":         "Internal server error while deleting the request",
+		"Error interno del servidor al eliminar las solicitudes":      "Internal server error while deleting the requests",
+		"Error interno del servidor al restaurar la solicitud":        "Internal server error while restoring the request",
+		"Error interno del servidor al cambiar el estado":             "Internal server error while changing the status",
+		"Error interno del servidor al asignar la solicitud":          "Internal server error while assigning the request",
+		"Error interno del servidor al consultar los servicios":       "Internal server error while reading the services",
+		"Error interno del servidor al calcular las estadísticas":     "Internal server error while computing the statistics",
+		"Error interno del servidor al consultar la auditoría":        "Internal server error while reading the audit log",
+	},
+}
+
+// messagePattern reconoce un mensaje con datos (una clave con %s o %d) y
+// da el formato de su traducción.
+type messagePattern struct {
+	re     *regexp.Regexp
+	format string
+}
+
+// messagePatterns son las claves con datos de cada catálogo, compiladas al
+// arrancar. Se prueban de la más larga a la más corta para que gane la más
+// específica.
+var messagePatterns = compileMessagePatterns()
+
+var messageVerbs = strings.NewReplacer("%s", "(.*?)", "%d", `(-?\d+)`)
+
+func compileMessagePatterns() map[string][]messagePattern {
+	patterns := make(map[string][]messagePattern, len(messageCatalogs))
+	for lang, catalog := range messageCatalogs {
+		var keys []string
+		for key := range catalog {
+			if strings.Contains(key, "%") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+		for _, key := range keys {
+			re := regexp.MustCompile("^" + messageVerbs.Replace(regexp.QuoteMeta(key)) + "$")
+			patterns[lang] = append(patterns[lang], messagePattern{re: re, format: catalog[key]})
+		}
+	}
+	return patterns
+}
+
+// translate devuelve msg en el idioma lang. Si el idioma o el mensaje no
+// están en los catálogos se devuelve msg tal cual, en español.
+func translate(lang, msg string) string {
+	catalog, ok := messageCatalogs[lang]
+	if !ok {
+		return msg
+	}
+	if translated, ok := catalog[msg]; ok {
+		return translated
+	}
+	for _, p := range messagePatterns[lang] {
+		if m := p.re.FindStringSubmatch(msg); m != nil {
+			args := make([]any, len(m)-1)
+			for i, arg := range m[1:] {
+				args[i] = arg
+			}
+			return fmt.Sprintf(p.format, args...)
+		}
+	}
+	return msg
+}
+
+// withLanguage elige el idioma de la respuesta y lo deja en la cabecera
+// Content-Language, de donde lo leen writeAPIError y responseLang.
+func withLanguage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", requestLang(r))
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestLang devuelve el idioma pedido con ?lang= o, si no, el de mayor
+// preferencia de Accept-Language que la API conozca. Por defecto, español.
+func requestLang(r *http.Request) string {
+	if lang := supportedLang(r.URL.Query().Get("lang")); lang != "" {
+		return lang
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if lang := supportedLang(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	if best == "" {
+		return defaultLang
+	}
+	return best
+}
+
+// supportedLang devuelve el idioma principal de una etiqueta como "en-US"
+// si la API lo conoce, o "" si no.
+func supportedLang(tag string) string {
+	primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if _, ok := messageCatalogs[primary]; ok || primary == defaultLang {
+		return primary
+	}
+	return ""
+}
+
+// responseLang devuelve el idioma elegido por withLanguage para la respuesta.
+func responseLang(w http.ResponseWriter) string {
+	if lang := w.Header().Get("Content-Language"); lang != "" {
+		return lang
+	}
+	return defaultLang
+}
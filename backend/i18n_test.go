@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// serveInLang pasa una petición por el router completo con la cabecera
+// Accept-Language indicada.
+func serveInLang(t *testing.T, method, path, acceptLanguage string) *httptest.ResponseRecorder {
+	t.Helper()
+	dbReady.Store(true)
+	t.Cleanup(func() { dbReady.Store(false) })
+
+	req := httptest.NewRequest(method, path, nil)
+	if acceptLanguage != "" {
+		req.Header.Set("Accept-Language", acceptLanguage)
+	}
+	rec := httptest.NewRecorder()
+	newRouter(Config{}).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEnglishValidationErrors(t *testing.T) {
+	openTestDB(t)
+	// Sin el router, que con Config{} limita los envíos a cero por minuto
+	req := httptest.NewRequest("POST", "/v1/submit-service", strings.NewReader(`{"nombre": "", "telefono": "12", "servicio": "Mantenimiento de PC"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept-Language", "en")
+	rec := httptest.NewRecorder()
+	withLanguage(http.HandlerFunc(submitServiceHandler)).ServeHTTP(rec, req)
+	wantStatus(t, rec, http.StatusBadRequest)
+	if got := rec.Header().Get("Content-Language"); got != langEN {
+		t.Errorf("Content-Language = %q, want en", got)
+	}
+
+	var apiErr APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Message != "Validation failed" {
+		t.Errorf("message = %q, want Validation failed", apiErr.Message)
+	}
+	want := map[string]string{
+		"nombre":   "The 'nombre' field is required",
+		"telefono": "Invalid phone number",
+	}
+	if len(apiErr.Errors) != len(want) {
+		t.Fatalf("errors = %+v", apiErr.Errors)
+	}
+	for _, e := range apiErr.Errors {
+		if e.Message != want[e.Field] {
+			t.Errorf("%s: %q, want %q", e.Field, e.Message, want[e.Field])
+		}
+	}
+	// El código no se traduce: los clientes lo comparan
+	if apiErr.Code != codeValidation {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeValidation)
+	}
+}
+
+func TestEnglishMessages(t *testing.T) {
+	openTestDB(t)
+	for _, tc := range []struct {
+		name, method, path, acceptLanguage string
+		want                               string
+	}{
+		{"ruta desconocida", "GET", "/v1/no-existe", "en", "Route not found"},
+		{"solicitud desconocida", "GET", "/v1/solicitudes/999", "en-US,en;q=0.9", "Request not found"},
+		{"parámetro con datos", "GET", "/v1/solicitudes?limit=-1", "en", "The 'limit' parameter must be a non-negative integer"},
+		{"?lang= manda sobre Accept-Language", "GET", "/v1/no-existe?lang=en", "es", "Route not found"},
+		{"sin Accept-Language", "GET", "/v1/no-existe", "", "Ruta no encontrada"},
+		{"idioma desconocido", "GET", "/v1/no-existe", "fr", "Ruta no encontrada"},
+		{"español preferido", "GET", "/v1/no-existe", "en;q=0.5, es", "Ruta no encontrada"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serveInLang(t, tc.method, tc.path, tc.acceptLanguage)
+			var apiErr APIError
+			if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+				t.Fatalf("cuerpo no es JSON: %v (%s)", err, rec.Body)
+			}
+			if apiErr.Message != tc.want {
+				t.Errorf("message = %q, want %q", apiErr.Message, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnglishWelcome(t *testing.T) {
+	rec := serveInLang(t, "GET", "/", "en")
+	if got := strings.TrimSpace(rec.Body.String()); got != "Welcome to the services API. Use /v1/submit-service to send data." {
+		t.Errorf("bienvenida = %q", got)
+	}
+	if vary := rec.Header().Values("Vary"); !strings.Contains(strings.Join(vary, ","), "Accept-Language") {
+		t.Errorf("Vary = %q, falta Accept-Language", vary)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	for _, tc := range []struct {
+		lang, msg, want string
+	}{
+		{langEN, "Solicitud no encontrada", "Request not found"},
+		{langEN, "El campo 'email' es obligatorio", "The 'email' field is required"},
+		{langEN, "El campo 'nombre' no puede superar 255 caracteres", "The 'nombre' field cannot exceed 255 characters"},
+		{langEN, "No se puede pasar de 'cerrado' a 'nuevo'", "Cannot change from 'cerrado' to 'nuevo'"},
+		{langEN, "Un mensaje sin traducción", "Un mensaje sin traducción"},
+		{langES, "Solicitud no encontrada", "Solicitud no encontrada"},
+		{"fr", "Solicitud no encontrada", "Solicitud no encontrada"},
+	} {
+		if got := translate(tc.lang, tc.msg); got != tc.want {
+			t.Errorf("translate(%s, %q) = %q, want %q", tc.lang, tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestRequestLang(t *testing.T) {
+	for _, tc := range []struct {
+		query, acceptLanguage, want string
+	}{
+		{"", "", langES},
+		{"", "en", langEN},
+		{"", "EN-gb", langEN},
+		{"", "fr, en;q=0.8, es;q=0.5", langEN},
+		{"", "en;q=0.3, es;q=0.7", langES},
+		{"", "en;q=abc", langES},
+		{"", "de, fr", langES},
+		{"lang=en", "es", langEN},
+		{"lang=xx", "en", langEN},
+	} {
+		req := httptest.NewRequest("GET", "/?"+tc.query, nil)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		if got := requestLang(req); got != tc.want {
+			t.Errorf("?%s con Accept-Language %q = %s, want %s", tc.query, tc.acceptLanguage, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tiempo por defecto que se recuerda una Idempotency-Key (IDEMPOTENCY_TTL).
+const defaultIdempotencyTTL = 24 * time.Hour
+
+var idempotencyTTL = defaultIdempotencyTTL
+
+// Cabecera con la que el cliente marca los reintentos de un mismo envío.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// errIdempotencyKeyReused indica que la clave ya se usó con otra solicitud.
+var errIdempotencyKeyReused = errors.New("Idempotency-Key reutilizada con otro cuerpo")
+
+// idempotencyKey lee la cabecera Idempotency-Key. Si no es válida escribe
+// la respuesta de error y devuelve false; sin cabecera devuelve "" y true.
+func idempotencyKey(w http.ResponseWriter, r *http.Request) (string, bool) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if len(key) > maxFieldLength {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "La cabecera Idempotency-Key no puede superar 255 caracteres")
+		return "", false
+	}
+	return key, true
+}
+
+// solicitudHash resume una solicitud ya normalizada para comprobar que un
+// reintento trae el mismo cuerpo que el envío original.
+func solicitudHash(s Solicitud) string {
+	b, _ := json.Marshal(s)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// findIdempotencyKey busca una clave procesada dentro de idempotencyTTL y
+// devuelve el id de la solicitud que creó. Si la clave se usó con otro
+// cuerpo devuelve errIdempotencyKeyReused.
+func findIdempotencyKey(ctx context.Context, q querier, key, hash string) (int64, bool, error) {
+	var (
+		id     int64
+		stored string
+	)
+	since := time.Now().UTC().Add(-idempotencyTTL)
+	err := q.QueryRowContext(ctx, rebind(`SELECT solicitud_id, request_hash FROM idempotency_keys
+		WHERE idempotency_key = ? AND created_at >= ?`), key, since).Scan(&id, &stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if stored != hash {
+		return id, true, errIdempotencyKeyReused
+	}
+	return id, true, nil
+}
+
+// saveIdempotencyKey guarda la clave junto a la solicitud creada. Antes
+// borra las claves caducadas, también la propia si se está reutilizando
+// pasado el TTL.
+func saveIdempotencyKey(ctx context.Context, q querier, key, hash string, id int64) error {
+	now := time.Now().UTC()
+	if _, err := q.ExecContext(ctx, rebind(`DELETE FROM idempotency_keys WHERE created_at < ?`), now.Add(-idempotencyTTL)); err != nil {
+		return err
+	}
+	_, err := q.ExecContext(ctx, rebind(`INSERT INTO idempotency_keys (idempotency_key, request_hash, solicitud_id, created_at)
+		VALUES (?, ?, ?, ?)`), key, hash, id, now)
+	return err
+}
+
+// replayIdempotent responde con la solicitud original si key ya se procesó,
+// o con 409 si se usó con otro cuerpo. Devuelve true si ya respondió.
+func replayIdempotent(w http.ResponseWriter, r *http.Request, key, hash string) bool {
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	id, found, err := findIdempotencyKey(ctx, db, key, hash)
+	if errors.Is(err, errIdempotencyKeyReused) {
+		writeIdempotencyConflict(w)
+		return true
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al comprobar la Idempotency-Key")
+		return true
+	}
+	if !found {
+		return false
+	}
+	writeIdempotentReplay(ctx, w, r, id)
+	return true
+}
+
+// replayConcurrentIdempotent responde a un envío cuya Idempotency-Key se
+// guardó desde otra petición simultánea después de comprobarla: la otra
+// ya hizo commit, así que se devuelve su solicitud (o 409 si el cuerpo es
+// otro) en lugar de un 500. Si aún no se ve, se pide reintentar.
+func replayConcurrentIdempotent(w http.ResponseWriter, r *http.Request, key, hash string) {
+	requestLogger(r).Info("Idempotency-Key guardada a la vez por otra petición")
+	if replayIdempotent(w, r, key, hash) {
+		return
+	}
+	w.Header().Set("Retry-After", "1")
+	writeError(w, http.StatusConflict, codeIdempotencyConflict, "Ya se está procesando una solicitud con esta Idempotency-Key, inténtalo de nuevo en unos segundos")
+}
+
+func writeIdempotencyConflict(w http.ResponseWriter) {
+	writeError(w, http.StatusConflict, codeIdempotencyConflict, "La cabecera Idempotency-Key ya se usó con una solicitud distinta")
+}
+
+// writeIdempotentReplay repite la respuesta 201 del envío original. Si la
+// solicitud se eliminó después, responde 404 como su GET.
+func writeIdempotentReplay(ctx context.Context, w http.ResponseWriter, r *http.Request, id int64) {
+	original, err := findSolicitud(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		requestLogger(r).Info("Idempotency-Key repetida de una solicitud eliminada", "id", id)
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+	requestLogger(r).Info("Idempotency-Key repetida, se devuelve la solicitud original", "id", id)
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newSolicitudResponse(original.public()))
+}
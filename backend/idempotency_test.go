@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// submitWithKey envía body con la cabecera Idempotency-Key.
+func submitWithKey(t *testing.T, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/v1/submit-service", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	submitServiceHandler(rec, req)
+	return rec
+}
+
+func responseID(t *testing.T, rec *httptest.ResponseRecorder) int64 {
+	t.Helper()
+	var resp struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("cuerpo no es JSON: %v (%q)", err, rec.Body.String())
+	}
+	return resp.ID
+}
+
+func TestIdempotencyKeyReplaysOriginal(t *testing.T) {
+	openTestDB(t)
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+
+	first := submitWithKey(t, "clave-1", body)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", first.Code, first.Body)
+	}
+	second := submitWithKey(t, "clave-1", body)
+	if second.Code != http.StatusCreated {
+		t.Fatalf("reintento: status = %d, body = %s", second.Code, second.Body)
+	}
+	if second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("falta Idempotent-Replayed en el reintento")
+	}
+	if a, b := responseID(t, first), responseID(t, second); a != b {
+		t.Errorf("id del reintento = %d, want %d", b, a)
+	}
+
+	var n int
+	db.QueryRow(`SELECT COUNT(*) FROM solicitudes`).Scan(&n)
+	if n != 1 {
+		t.Errorf("solicitudes guardadas = %d, want 1", n)
+	}
+}
+
+// El reintento de una solicitud eliminada o purgada no debe acabar en 500.
+func TestIdempotencyKeyReplayOfDeleted(t *testing.T) {
+	for name, remove := range map[string]string{
+		"eliminada": `UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		"purgada":   `DELETE FROM solicitudes WHERE id = ?`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			openTestDB(t)
+			body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+			first := submitWithKey(t, "clave-1", body)
+			wantStatus(t, first, http.StatusCreated)
+			if _, err := db.Exec(remove, responseID(t, first)); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := submitWithKey(t, "clave-1", body)
+			wantStatus(t, rec, http.StatusNotFound)
+			var apiErr APIError
+			json.Unmarshal(rec.Body.Bytes(), &apiErr)
+			if apiErr.Code != codeNotFound {
+				t.Errorf("code = %q, want %q", apiErr.Code, codeNotFound)
+			}
+			if rec.Header().Get("Idempotent-Replayed") != "" {
+				t.Error("Idempotent-Replayed en una respuesta de error")
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyWithOtherBody(t *testing.T) {
+	openTestDB(t)
+	if rec := submitWithKey(t, "clave-1", solicitudBody("Ana", "8095551111", "Mantenimiento de PC")); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	rec := submitWithKey(t, "clave-1", solicitudBody("Luis", "8095552222", "Mantenimiento de PC"))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (body %s)", rec.Code, rec.Body)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codeIdempotencyConflict {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeIdempotencyConflict)
+	}
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	openTestDB(t)
+	ctx := context.Background()
+	if err := saveIdempotencyKey(ctx, db, "clave-1", "hash", 1); err != nil {
+		t.Fatal(err)
+	}
+	err := saveIdempotencyKey(ctx, db, "clave-1", "hash", 2)
+	if err == nil {
+		t.Fatal("se guardó dos veces la misma clave")
+	}
+	if !isDuplicateKeyError(err) {
+		t.Errorf("isDuplicateKeyError(%v) = false", err)
+	}
+	if isDuplicateKeyError(errors.New("connection refused")) || isDuplicateKeyError(nil) {
+		t.Error("isDuplicateKeyError acepta errores que no son de clave duplicada")
+	}
+}
+
+// La petición que pierde la carrera ya comprobó la clave antes de que la
+// otra la guardara; al fallar el INSERT debe devolver la solicitud de la
+// otra.
+func TestConcurrentIdempotencyKeyReplays(t *testing.T) {
+	openTestDB(t)
+	s := Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"}
+	id := seedSolicitud(t, s)
+	hash := solicitudHash(s)
+	if err := saveIdempotencyKey(context.Background(), db, "clave-1", hash, id); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	replayConcurrentIdempotent(rec, httptest.NewRequest("POST", "/v1/submit-service", nil), "clave-1", hash)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("falta Idempotent-Replayed")
+	}
+	if got := responseID(t, rec); got != id {
+		t.Errorf("id = %d, want %d", got, id)
+	}
+
+	// Con otro cuerpo es un conflicto, como en un reintento normal
+	rec = httptest.NewRecorder()
+	replayConcurrentIdempotent(rec, httptest.NewRequest("POST", "/v1/submit-service", nil), "clave-1", "otro")
+	if rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") != "" {
+		t.Errorf("otro cuerpo: status = %d, Retry-After = %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestConcurrentIdempotencyKeyStillInProgress(t *testing.T) {
+	openTestDB(t)
+	rec := httptest.NewRecorder()
+	replayConcurrentIdempotent(rec, httptest.NewRequest("POST", "/v1/submit-service", nil), "clave-1", "hash")
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want 409 (body %s)", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codeIdempotencyConflict {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeIdempotencyConflict)
+	}
+}
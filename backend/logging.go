@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// logger es el logger base de la aplicación; main lo configura con
+// LOG_LEVEL. Los handlers deben usar requestLogger(r), que añade los datos
+// de la petición.
+var logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+type loggerKey struct{}
+
+// newLogger crea un logger JSON con el nivel indicado.
+func newLogger(level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level}))
+}
+
+// withRequestLogger guarda en el contexto de cada petición un logger con su
+// request_id, método y ruta. Debe ir dentro de withRequestID.
+func withRequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := logger.With("request_id", requestIDFrom(r.Context()), "method", r.Method, "path", r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loggerKey{}, l)))
+	})
+}
+
+// quietAccessLogPaths son las rutas que llaman los monitores con mucha
+// frecuencia: sus peticiones correctas se registran solo en nivel debug.
+// Se comparan con el patrón de chi sin el prefijo de versión, así que
+// cubren también sus alias bajo /v1.
+var quietAccessLogPaths = map[string]bool{
+	"/health": true,
+	"/readyz": true,
+	"/livez":  true,
+	"/ping":   true,
+}
+
+// withAccessLog escribe una línea de log por petición con el estado, la
+// duración y los bytes enviados. Debe ir dentro de withRequestLogger para
+// incluir el request_id, y por fuera del resto de middlewares.
+func withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+		} else if quietAccessLog(r) && rec.status < http.StatusBadRequest {
+			level = slog.LevelDebug
+		}
+		requestLogger(r).Log(r.Context(), level, "Petición atendida",
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.bytes,
+			"remote_ip", clientIP(r))
+	})
+}
+
+// quietAccessLog indica si la ruta que atendió la petición es de
+// quietAccessLogPaths. Debe llamarse después de servirla, cuando chi ya
+// ha resuelto el patrón.
+func quietAccessLog(r *http.Request) bool {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return false
+	}
+	return quietAccessLogPaths[strings.TrimPrefix(rctx.RoutePattern(), "/v1")]
+}
+
+// requestLogger devuelve el logger de la petición, o el logger base si la
+// petición no pasó por withRequestLogger.
+func requestLogger(r *http.Request) *slog.Logger {
+	if l, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return logger
+}
+
+// fatal registra el error y termina el proceso, como hacía log.Fatal.
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// captureLogs cambia el logger por uno en nivel debug que escribe en el
+// buffer devuelto mientras dura el test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var logs bytes.Buffer
+	prev := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	t.Cleanup(func() { logger = prev })
+	return &logs
+}
+
+// accessLogLevel devuelve el nivel de la línea "Petición atendida".
+func accessLogLevel(t *testing.T, logs *bytes.Buffer) string {
+	t.Helper()
+	for line := range bytes.Lines(logs.Bytes()) {
+		var entry struct {
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}
+		if json.Unmarshal(line, &entry) == nil && entry.Msg == "Petición atendida" {
+			return entry.Level
+		}
+	}
+	t.Fatalf("no hay línea de acceso en %s", logs)
+	return ""
+}
+
+func TestAccessLogQuietProbes(t *testing.T) {
+	openTestDB(t)
+	for _, tc := range []struct {
+		method, path string
+		level        string
+	}{
+		{"GET", "/health", "DEBUG"},
+		{"HEAD", "/health", "DEBUG"},
+		{"GET", "/readyz", "DEBUG"},
+		{"GET", "/livez", "DEBUG"},
+		{"GET", "/ping", "DEBUG"},
+		{"GET", "/version", "INFO"},
+		{"GET", "/health/otra", "INFO"},
+	} {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			logs := captureLogs(t)
+			serveAPI(t, tc.method, tc.path, "")
+			if got := accessLogLevel(t, logs); got != tc.level {
+				t.Errorf("nivel = %s, want %s", got, tc.level)
+			}
+		})
+	}
+}
+
+// Un monitor que falla sí debe verse en los logs.
+func TestAccessLogFailedProbe(t *testing.T) {
+	useUnreachableDB(t)
+	logs := captureLogs(t)
+	rec := serveAPI(t, "GET", "/health", "")
+	wantStatus(t, rec, http.StatusServiceUnavailable)
+	if got := accessLogLevel(t, logs); got != "WARN" {
+		t.Errorf("nivel = %s, want WARN", got)
+	}
+}
+
+// Los alias bajo /v1 se comparan por el patrón de la ruta, no por la URL.
+func TestAccessLogQuietAliases(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(withRequestLogger, withAccessLog)
+	r.Get("/ping", pingHandler)
+	r.Route("/v1", func(r chi.Router) {
+		r.Get("/ping", pingHandler)
+		r.Get("/livez", livezHandler)
+	})
+	for _, tc := range []struct {
+		path, level string
+	}{
+		{"/ping", "DEBUG"},
+		{"/ping?origen=monitor", "DEBUG"},
+		{"/v1/ping", "DEBUG"},
+		{"/v1/livez", "DEBUG"},
+		{"/v1/ping/", "INFO"},
+		{"/v2/ping", "INFO"},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			logs := captureLogs(t)
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.path, nil))
+			if got := accessLogLevel(t, logs); got != tc.level {
+				t.Errorf("nivel = %s, want %s", got, tc.level)
+			}
+		})
+	}
+}
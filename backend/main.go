@@ -1,136 +1,382 @@
-package main
-
-import (
-	"database/sql" // Para la conexión a la base de datos
-	"encoding/json"
-	"fmt"
-	"log"
-	"net/http"
-	"os" // Para leer variables de entorno
-
-	_ "github.com/go-sql-driver/mysql" // <--- Driver para MySQL
-)
-
-// Solicitud representa la estructura de los datos que recibiremos del formulario
-type Solicitud struct {
-	Nombre   string `json:"nombre"`
-	Telefono string `json:"telefono"`
-	Servicio string `json:"servicio"`
-}
-
-// Global variable for the database connection (for simplicity in this example)
-var db *sql.DB
-
-func main() {
-	// --- Configuración de la Base de Datos (MySQL en este ejemplo) ---
-	// Railway inyecta la URL de la base de datos en una variable de entorno.
-	// Para MySQL en Railway, la variable de entorno es normalmente MYSQL_URL.
-	dbURL := os.Getenv("MYSQL_URL") // <--- Usamos MYSQL_URL para Railway
-	if dbURL == "" {
-		log.Fatal("La variable de entorno MYSQL_URL no está configurada. Asegúrate de que Railway la esté inyectando o configúrala localmente para pruebas.")
-	}
-
-	var err error
-	// Abre la conexión a la base de datos
-	db, err = sql.Open("mysql", dbURL) // <--- Conector "mysql"
-
-	if err != nil {
-		log.Fatalf("Error al conectar a la base de datos: %v", err)
-	}
-	defer db.Close() // Asegúrate de cerrar la conexión cuando la aplicación se detenga
-
-	// Prueba la conexión
-	err = db.Ping()
-	if err != nil {
-		log.Fatalf("Error al hacer ping a la base de datos: %v", err)
-	}
-	fmt.Println("Conexión a la base de datos MySQL establecida con éxito.")
-
-	// --- Crear la tabla si no existe (solo si es la primera vez) ---
-	// Adapta la consulta SQL para MySQL.
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS solicitudes (
-		id INT AUTO_INCREMENT PRIMARY KEY,
-		nombre VARCHAR(255) NOT NULL,
-		telefono VARCHAR(255) NOT NULL,
-		servicio VARCHAR(255) NOT NULL,
-		fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	` // <--- Consulta SQL para MySQL
-	_, err = db.Exec(createTableSQL)
-	if err != nil {
-		log.Fatalf("Error al crear la tabla 'solicitudes': %v", err)
-	}
-	fmt.Println("Tabla 'solicitudes' verificada/creada con éxito.")
-
-	// --- Configuración de la API ---
-	// La ruta principal se configura para manejar CORS y redirigir
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*") // Permitir cualquier origen (¡CUIDADO EN PRODUCCIÓN!)
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With")
-
-		// Manejar pre-flight requests (OPTIONS)
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-
-		// Si la ruta es el endpoint de envío, pasamos al handler específico
-		if r.URL.Path == "/submit-service" {
-			submitServiceHandler(w, r)
-			return
-		}
-
-		// Si es cualquier otra ruta, mostramos un mensaje por defecto
-		http.Error(w, "Bienvenido a la API de servicios. Usa /submit-service para enviar datos.", http.StatusOK)
-	})
-
-	// Obtener el puerto del entorno (Railway lo inyecta en PORT)
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080" // Puerto por defecto para desarrollo local
-	}
-
-	fmt.Printf("Servidor Go escuchando en el puerto :%s\n", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
-}
-
-func submitServiceHandler(w http.ResponseWriter, r *http.Request) {
-	// Configurar CORS para esta respuesta específica también
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Access-Control-Allow-Headers, Authorization, X-Requested-With")
-	w.Header().Set("Content-Type", "application/json")
-
-	if r.Method == "OPTIONS" {
-		w.WriteHeader(http.StatusOK)
-		return
-	}
-
-	if r.Method != "POST" {
-		http.Error(w, `{"message": "Método no permitido"}`, http.StatusMethodNotAllowed)
-		return
-	}
-
-	var solicitud Solicitud
-	err := json.NewDecoder(r.Body).Decode(&solicitud)
-	if err != nil {
-		http.Error(w, `{"message": "Error al decodificar la solicitud JSON"}`, http.StatusBadRequest)
-		return
-	}
-
-	log.Printf("Solicitud recibida para el servicio '%s': Nombre='%s', Teléfono='%s'", solicitud.Servicio, solicitud.Nombre, solicitud.Telefono)
-
-	// --- Insertar en la base de datos ---
-	// Adapta la consulta SQL para MySQL con marcadores de posición "?"
-	insertSQL := `INSERT INTO solicitudes (nombre, telefono, servicio) VALUES (?, ?, ?)` // <--- Consulta SQL para MySQL
-	_, err = db.Exec(insertSQL, solicitud.Nombre, solicitud.Telefono, solicitud.Servicio)
-	if err != nil {
-		log.Printf("Error al insertar en la base de datos: %v", err)
-		http.Error(w, `{"message": "Error interno del servidor al guardar la solicitud"}`, http.StatusInternalServerError)
-		return
-	}
-
-	json.NewEncoder(w).Encode(map[string]string{"message": "Solicitud recibida con éxito!"})
-}
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql" // Para la conexión a la base de datos
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Solicitud representa la estructura de los datos que recibiremos del formulario
+type Solicitud struct {
+	Nombre   string `json:"nombre"`
+	Telefono string `json:"telefono"`
+	Servicio string `json:"servicio"`
+	// Email es opcional; vacío si el cliente prefiere que le llamen
+	Email string `json:"email"`
+	// Mensaje es la descripción opcional del problema, hasta
+	// maxMensajeLength caracteres
+	Mensaje string `json:"mensaje"`
+	// HorarioPreferido es la franja en la que prefiere que le llamen
+	// (mañana, tarde o noche); vacío si le da igual
+	HorarioPreferido string `json:"horario_preferido"`
+}
+
+// Tiempo máximo que esperamos a las peticiones en curso al apagar el servidor
+const shutdownTimeout = 10 * time.Second
+
+// Timeouts por defecto del servidor HTTP. Sin ellos un cliente lento puede
+// mantener conexiones abiertas indefinidamente (slowloris).
+const (
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+)
+
+// serverTimeouts son los timeouts de http.Server, configurables con
+// HTTP_READ_HEADER_TIMEOUT, HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT y
+// HTTP_IDLE_TIMEOUT, más el de cada petición en los handlers
+// (REQUEST_TIMEOUT, 0 lo desactiva).
+type serverTimeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+	Request    time.Duration
+}
+
+// Global variable for the database connection (for simplicity in this example)
+var db *sql.DB
+
+// dbReady indica que db ya está conectada y la tabla verificada
+var dbReady atomic.Bool
+
+func main() {
+	cfg, err := LoadConfig()
+	if err != nil {
+		exitOnConfigErrors(err)
+	}
+	cfg.apply()
+
+	// --- Logging estructurado ---
+	logger = newLogger(cfg.LogLevel)
+	slog.SetDefault(logger)
+	logger.Info("Iniciando rayner_tec", "version", version, "commit", commit, "build_time", buildTime)
+
+	// El resumen de la configuración sale al conectar con la base de datos
+	// (logStartupReport); aquí solo se avisa de lo que no debería llegar a
+	// producción
+	if cfg.AllowedServices == nil {
+		logger.Warn("ALLOWED_SERVICES=*: se acepta cualquier servicio que cumpla SERVICIO_REGEX")
+	}
+	if cfg.MaintenanceMode {
+		logger.Warn("Modo mantenimiento activado (MAINTENANCE_MODE): se rechazan las escrituras")
+	}
+	if cfg.AdminAPIKey == "" {
+		logger.Warn("ADMIN_API_KEY no está configurada: /solicitudes no requiere autenticación (solo para desarrollo)")
+	}
+	if len(cfg.AllowedOrigins) == 0 {
+		logger.Warn("ALLOWED_ORIGINS no está configurada: se permite cualquier origen (solo para desarrollo)")
+	}
+	if !cfg.UseTLS() {
+		logger.Info("Modo HTTP sin TLS (TLS_CERT_FILE y TLS_KEY_FILE no configuradas)")
+	}
+	// os.Exit no ejecuta los defer: run los ejecuta todos antes de volver
+	if err := run(cfg); err != nil {
+		os.Exit(1)
+	}
+}
+
+// run arranca el servidor y la conexión a la base de datos y espera a la
+// señal de apagado. Si el arranque falla, registra el motivo y devuelve el
+// error después de cerrar lo que ya estuviera abierto.
+func run(cfg Config) error {
+	shutdownTracing, err := initTracing(context.Background(), cfg)
+	if err != nil {
+		logger.Error("No se pudo configurar el envío de trazas", "error", err)
+		return err
+	}
+	// Se envían las trazas pendientes también si el arranque falla
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Error al enviar las trazas pendientes", "error", err)
+		}
+	}()
+
+	server := newServer(cfg)
+	useTLS := cfg.UseTLS()
+
+	// Abrir el puerto antes de arrancar para fallar enseguida si está ocupado
+	// y registrar la dirección real
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		logger.Error("No se pudo escuchar en la dirección configurada", "addr", server.Addr, "error", err)
+		return err
+	}
+	logger.Info("Servidor Go escuchando", "addr", ln.Addr().String(), "tls", useTLS)
+
+	go func() {
+		var err error
+		if useTLS {
+			err = server.ServeTLS(ln, cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.Serve(ln)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Error del servidor HTTP", "error", err)
+		}
+	}()
+
+	// --- Conexión a la base de datos ---
+	// El servidor ya está escuchando: /livez responde desde el principio y
+	// /readyz devuelve 503 hasta que la base de datos esté lista.
+	db, err = openDB(cfg.DBDriver, cfg.DatabaseURL, cfg.DBPool, cfg.DBRetry)
+	if err := logStartupReport(cfg, ln.Addr().String(), err); err != nil {
+		return err
+	}
+	defer db.Close() // Asegúrate de cerrar la conexión cuando la aplicación se detenga
+	if cfg.WriteBuffer.Enabled {
+		solicitudBuffer = newWriteBuffer(cfg.WriteBuffer)
+		go solicitudBuffer.run()
+	}
+	if cfg.Fallback.Path != "" {
+		fallbackSpool = startFallbackStore(cfg.Fallback)
+	}
+	var purge *purgeJob
+	if cfg.Purge.Retention > 0 {
+		purge = startPurgeJob(cfg.Purge)
+	}
+	dbReady.Store(true)
+
+	// --- Apagado ordenado ---
+	// Railway envía SIGTERM al desplegar; dejamos terminar las peticiones en
+	// curso antes de cerrar la conexión a la base de datos.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+
+	logger.Info("Señal de apagado recibida, esperando a que terminen las peticiones en curso")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Error al apagar el servidor HTTP", "error", err)
+	}
+	// Las solicitudes encoladas se guardan antes de esperar a las
+	// notificaciones, que se lanzan al guardarlas
+	if solicitudBuffer != nil {
+		solicitudBuffer.Close(shutdownCtx)
+	}
+	if purge != nil {
+		purge.Stop(shutdownCtx)
+	}
+	if fallbackSpool != nil {
+		fallbackSpool.Stop(shutdownCtx)
+	}
+	waitNotifications(shutdownCtx)
+	logger.Info("Servidor HTTP detenido, cerrando la conexión a la base de datos")
+	return nil
+}
+
+// newServer crea el servidor HTTP con las rutas y la configuración TLS.
+func newServer(cfg Config) *http.Server {
+	server := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           newRouter(cfg),
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		ReadTimeout:       cfg.Timeouts.Read,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+	}
+
+	// --- TLS opcional ---
+	// Detrás del proxy de Railway no hace falta; fuera de él se puede servir
+	// HTTPS directamente indicando el certificado y la clave.
+	if cfg.UseTLS() {
+		server.TLSConfig = &tls.Config{MinVersion: cfg.TLSMinVersion}
+	}
+	return server
+}
+
+func submitServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	validateOnly, err := strconv.ParseBool(r.URL.Query().Get("validate_only"))
+	if err != nil && r.URL.Query().Has("validate_only") {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, "El parámetro 'validate_only' debe ser true o false")
+		return
+	}
+	idemKey, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
+	solicitud, extras, ok := decodeSubmission(w, r)
+	if extras.Spam {
+		// Responder como si todo hubiera ido bien para que el bot no insista
+		requestLogger(r).Debug("blocked_spam", "ip", clientIP(r), "field", honeypotField)
+		json.NewEncoder(w).Encode(map[string]string{"message": translate(responseLang(w), "Solicitud recibida")})
+		return
+	}
+	if !ok {
+		return
+	}
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("solicitud.servicio", solicitud.Servicio))
+
+	// Con ?validate_only=true el formulario comprueba los campos con las
+	// mismas reglas sin guardar nada ni gastar el token del captcha
+	if validateOnly {
+		json.NewEncoder(w).Encode(map[string]bool{"valid": true})
+		return
+	}
+
+	// Un reintento con la misma Idempotency-Key se resuelve antes del
+	// captcha: el token de reCAPTCHA solo se puede verificar una vez.
+	var idemHash string
+	if idemKey != "" {
+		idemHash = solicitudHash(solicitud)
+		if replayIdempotent(w, r, idemKey, idemHash) {
+			return
+		}
+	}
+
+	if captchaVerifier != nil {
+		score, err := captchaVerifier.Verify(r.Context(), extras.CaptchaToken, clientIP(r))
+		if errors.Is(err, errCaptchaRejected) {
+			requestLogger(r).Info("Captcha rechazado", "ip", clientIP(r), "score", score, "error", err)
+			writeError(w, http.StatusBadRequest, codeCaptchaFailed, "No se pudo verificar que no eres un robot. Recarga la página e inténtalo de nuevo.")
+			return
+		}
+		if err != nil {
+			requestLogger(r).Error("Error al verificar el captcha", "error", err, "status", http.StatusServiceUnavailable)
+			writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "No se pudo verificar el captcha, inténtalo de nuevo en unos segundos")
+			return
+		}
+		requestLogger(r).Debug("Captcha verificado", "score", score)
+	}
+
+	requestLogger(r).Info("Solicitud recibida", "servicio", solicitud.Servicio)
+
+	// En el modo de escritura en lotes no se espera a la base de datos: no
+	// hay id que devolver ni se comprueban duplicados ni Idempotency-Key.
+	// El cupo sí, porque después de responder 202 ya no se puede rechazar
+	if solicitudBuffer != nil {
+		if !checkBufferedQuota(w, r, solicitud.Servicio) {
+			return
+		}
+		acceptBuffered(w, r, solicitud)
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	// --- Insertar en la base de datos ---
+	// La comprobación de duplicados y el INSERT van en una transacción: si
+	// algo falla antes del commit no queda nada guardado.
+	var (
+		dup      SolicitudGuardada
+		found    bool
+		replayed bool
+		id       int64
+	)
+	err = withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		if idemKey != "" {
+			// Otro reintento con la misma clave pudo terminar mientras se
+			// verificaba el captcha
+			if id, replayed, err = findIdempotencyKey(ctx, tx, idemKey, idemHash); err != nil || replayed {
+				return err
+			}
+		}
+		// Si ya existe una solicitud idéntica reciente (doble clic), se
+		// devuelve esa en lugar de insertar otra, para que reenviar sea
+		// idempotente.
+		if dup, found, err = findRecentDuplicate(ctx, tx, solicitud); err != nil {
+			return err
+		}
+		if found {
+			dup, err = resolveDuplicate(ctx, tx, dup)
+			return err
+		}
+		if err := checkDailyQuota(ctx, tx, solicitud.Servicio); err != nil {
+			return err
+		}
+		var inserted bool
+		if id, inserted, err = upsertSolicitud(ctx, tx, solicitud, requestClientInfo(r)); err != nil {
+			return err
+		}
+		if !inserted {
+			// Otra petición idéntica se guardó a la vez y el upsert ya le
+			// aplicó dedupStrategy
+			found = true
+			dup, err = lockDuplicate(ctx, tx, id)
+			return err
+		}
+		if idemKey == "" {
+			return nil
+		}
+		return saveIdempotencyKey(ctx, tx, idemKey, idemHash, id)
+	})
+	if errors.Is(err, errIdempotencyKeyReused) {
+		writeIdempotencyConflict(w)
+		return
+	}
+	if idemKey != "" && isDuplicateKeyError(err) {
+		replayConcurrentIdempotent(w, r, idemKey, idemHash)
+		return
+	}
+	if errors.Is(err, errDailyQuotaReached) {
+		writeDailyQuotaReached(w, r, solicitud.Servicio)
+		return
+	}
+	if err != nil && fallbackSpool != nil && isDBUnavailableError(err) {
+		acceptSpooled(w, r, solicitud, err)
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al guardar la solicitud")
+		return
+	}
+	if replayed {
+		writeIdempotentReplay(ctx, w, r, id)
+		return
+	}
+	if found {
+		requestLogger(r).Info("Solicitud duplicada, se devuelve la existente", "id", dup.ID, "servicio", dup.Servicio, "strategy", dedupStrategy)
+		if dedupStrategy != dedupSkip {
+			responseCache.invalidate()
+		}
+		json.NewEncoder(w).Encode(newSolicitudResponse(dup.public()))
+		return
+	}
+	recordSolicitudCreada(solicitud.Servicio)
+	responseCache.invalidate()
+
+	// Releer el registro para devolver la fecha_creacion asignada por la base
+	// de datos; el frontend muestra el id como número de confirmación.
+	creada, err := findSolicitud(ctx, id)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud creada", "id", id)
+		return
+	}
+
+	notifyNuevaSolicitud(requestLogger(r), creada)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newSolicitudResponse(creada.public()))
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMain silencia el logger para que la salida de los tests sea legible.
+func TestMain(m *testing.M) {
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	os.Exit(m.Run())
+}
+
+// submit envía body a submitServiceHandler como lo haría el formulario.
+func submit(t *testing.T, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/v1/submit-service", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	submitServiceHandler(rec, req)
+	return rec
+}
+
+// solicitudBody devuelve el JSON de una solicitud válida del formulario.
+func solicitudBody(nombre, telefono, servicio string) string {
+	return fmt.Sprintf(`{"nombre": %q, "telefono": %q, "servicio": %q}`, nombre, telefono, servicio)
+}
+
+// serveAPI envía una petición al router completo, con la base de datos
+// lista y sin ADMIN_API_KEY.
+func serveAPI(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	dbReady.Store(true)
+	t.Cleanup(func() { dbReady.Store(false) })
+
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	rec := httptest.NewRecorder()
+	newRouter(Config{}).ServeHTTP(rec, req)
+	return rec
+}
+
+// wantStatus falla el test si rec no tiene el estado want.
+func wantStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Fatalf("status = %d, want %d (body %s)", rec.Code, want, rec.Body)
+	}
+}
+
+func TestSubmitReturnsCreatedSolicitud(t *testing.T) {
+	openTestDB(t)
+	rec := submit(t, solicitudBody(" Ana ", "809-555-1111", "Mantenimiento de PC"))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	id, _ := resp["id"].(float64)
+	if id <= 0 {
+		t.Errorf("id = %v, want el de la fila creada", resp["id"])
+	}
+	fecha, _ := resp["fecha_creacion"].(string)
+	if _, err := time.Parse(time.RFC3339, fecha); err != nil {
+		t.Errorf("fecha_creacion = %q: %v", fecha, err)
+	}
+	if resp["nombre"] != "Ana" || resp["servicio"] != "Mantenimiento de PC" {
+		t.Errorf("respuesta = %v", resp)
+	}
+	// Los datos internos no se devuelven a quien envía el formulario
+	for _, field := range []string{"ip_address", "user_agent", "submission_count"} {
+		if _, ok := resp[field]; ok {
+			t.Errorf("la respuesta incluye %s", field)
+		}
+	}
+
+	var stored string
+	db.QueryRow(`SELECT nombre FROM solicitudes WHERE id = ?`, int64(id)).Scan(&stored)
+	if stored != "Ana" {
+		t.Errorf("nombre guardado = %q", stored)
+	}
+}
+
+func TestSubmitRollsBackWhenLaterStatementFails(t *testing.T) {
+	openTestDB(t)
+	// La solicitud se inserta, pero falla guardar la Idempotency-Key en la
+	// misma transacción
+	if _, err := db.Exec(`CREATE TRIGGER fail_idempotency BEFORE INSERT ON idempotency_keys
+		BEGIN SELECT RAISE(ABORT, 'fallo simulado'); END`); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := submitWithKey(t, "clave-1", solicitudBody("Ana", "8095551111", "Mantenimiento de PC"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500 (body %s)", rec.Code, rec.Body)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codeInternal {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeInternal)
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("quedaron %d solicitudes guardadas", n)
+	}
+}
+
+func TestSubmitValidateOnly(t *testing.T) {
+	openTestDB(t)
+	post := func(query, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/submit-service"+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		submitServiceHandler(rec, req)
+		return rec
+	}
+
+	rec := post("?validate_only=true", solicitudBody("Ana", "8095551111", "Mantenimiento de PC"))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"valid":true}` {
+		t.Errorf("válida: status = %d, body = %s", rec.Code, rec.Body)
+	}
+	rec = post("?validate_only=1", solicitudBody("Ana", "12", "Mantenimiento de PC"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("inválida: status = %d, want 400", rec.Code)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "telefono" {
+		t.Errorf("error = %+v, want el campo telefono", apiErr)
+	}
+	if rec := post("?validate_only=quizas", solicitudBody("Ana", "8095551111", "Mantenimiento de PC")); rec.Code != http.StatusBadRequest {
+		t.Errorf("validate_only=quizas: status = %d, want 400", rec.Code)
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("se guardaron %d solicitudes en modo validación", n)
+	}
+
+	// validate_only=false es un envío normal
+	if rec := post("?validate_only=false", solicitudBody("Ana", "8095551111", "Mantenimiento de PC")); rec.Code != http.StatusCreated {
+		t.Errorf("validate_only=false: status = %d, want 201", rec.Code)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+)
+
+// Segundos que se piden esperar en Retry-After durante el mantenimiento
+const maintenanceRetryAfter = 120
+
+// maintenanceMode rechaza las escrituras mientras se trabaja en la base de
+// datos (migraciones). Empieza con MAINTENANCE_MODE y se cambia en marcha
+// con PUT /v1/maintenance.
+var maintenanceMode atomic.Bool
+
+// rejectInMaintenance responde 503 a las escrituras durante el
+// mantenimiento. Las lecturas siguen funcionando.
+func rejectInMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if maintenanceMode.Load() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+			writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "En mantenimiento")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setMaintenance cambia el modo mantenimiento y lo registra si cambia.
+func setMaintenance(enabled bool, by string) {
+	if maintenanceMode.Swap(enabled) == enabled {
+		return
+	}
+	if enabled {
+		logger.Warn("Modo mantenimiento activado: se rechazan las escrituras", "by", by)
+	} else {
+		logger.Info("Modo mantenimiento desactivado", "by", by)
+	}
+}
+
+// maintenanceHandler devuelve {"enabled": true|false}.
+func maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"enabled": maintenanceMode.Load()})
+}
+
+// setMaintenanceHandler activa o desactiva el mantenimiento con un cuerpo
+// {"enabled": true|false}. No pasa por rejectInMaintenance para poder
+// desactivarlo.
+func setMaintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := decodeStrict(body, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, decodeErrorResponse(err))
+		return
+	}
+	if req.Enabled == nil {
+		writeAPIError(w, http.StatusBadRequest, APIError{Message: "El campo 'enabled' es obligatorio", Code: codeValidation, Field: "enabled"})
+		return
+	}
+	setMaintenance(*req.Enabled, adminActor(r))
+	maintenanceHandler(w, r)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Métricas Prometheus expuestas en /metrics cuando METRICS_ENABLED=true.
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Peticiones HTTP atendidas, por ruta, método y estado.",
+	}, []string{"path", "method", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duración de las peticiones HTTP en segundos.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path", "method"})
+
+	solicitudesCreadasTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "solicitudes_creadas_total",
+		Help: "Solicitudes guardadas desde /submit-service, por servicio.",
+	}, []string{"servicio"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "db_open_connections",
+		Help: "Conexiones abiertas con la base de datos (db.Stats).",
+	}, func() float64 {
+		if !dbReady.Load() {
+			return 0
+		}
+		return float64(db.Stats().OpenConnections)
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "solicitudes_write_buffer_length",
+		Help: "Solicitudes encoladas pendientes de guardar (WRITE_BUFFER_ENABLED).",
+	}, func() float64 {
+		if !dbReady.Load() || solicitudBuffer == nil {
+			return 0
+		}
+		return float64(solicitudBuffer.Len())
+	})
+)
+
+// metricsHandler sirve las métricas en el formato de Prometheus.
+var metricsHandler = promhttp.Handler()
+
+// metricsMiddleware cuenta las peticiones y mide su duración. La ruta se
+// etiqueta con el patrón de chi (/solicitudes/{id}) para que las etiquetas
+// no crezcan sin límite; las rutas desconocidas se agrupan en "other".
+func metricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		path := routePattern(r)
+		if path == "" {
+			path = "other"
+		}
+		httpRequestsTotal.WithLabelValues(path, r.Method, strconv.Itoa(rec.status)).Inc()
+		httpRequestDuration.WithLabelValues(path, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern devuelve el patrón de chi con el que se atendió la petición
+// (/solicitudes/{id}), o "" si no coincidió con ninguna ruta.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// statusRecorder guarda el código de estado y los bytes que escribe el
+// handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
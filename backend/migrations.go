@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Las migraciones son ficheros migrations/<driver>/NNNN_descripcion.sql que
+// se aplican una sola vez, en orden de número. Cada driver tiene su propia
+// carpeta con las mismas versiones. Nunca se edita una migración ya
+// publicada: los cambios de esquema van en un fichero nuevo.
+//
+//go:embed migrations/*/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations lee las migraciones embebidas del driver ordenadas por
+// versión.
+func loadMigrations(driver string) ([]migration, error) {
+	names, err := fs.Glob(migrationFiles, path.Join("migrations", driver, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no hay migraciones para el driver %q", driver)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, name := range names {
+		base := path.Base(name)
+		prefix, _, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("nombre de migración inválido %q: debe empezar por un número", base)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migraciones %q y %q con la misma versión", other, base)
+		}
+		seen[version] = base
+
+		content, err := migrationFiles.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{Version: version, Name: base, SQL: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// runMigrations crea la tabla schema_migrations si hace falta y aplica, en
+// orden, las migraciones que todavía no estén registradas en ella.
+func runMigrations(conn *sql.DB, driver string) error {
+	migrations, err := loadMigrations(driver)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("crear schema_migrations: %v", err)
+	}
+
+	applied := make(map[int]bool)
+	rows, err := conn.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("leer schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return fmt.Errorf("leer schema_migrations: %v", err)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("leer schema_migrations: %v", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if err := applyMigration(conn, m); err != nil {
+			return fmt.Errorf("migración %s: %v", m.Name, err)
+		}
+		logger.Info("Migración aplicada", "version", m.Version, "name", m.Name)
+	}
+	logger.Info("Esquema de la base de datos al día", "migrations", len(migrations))
+	return nil
+}
+
+// applyMigration ejecuta una migración y la registra en la misma
+// transacción. Ojo: en MySQL las sentencias DDL hacen commit implícito, así
+// que una migración con varias sentencias DDL no es atómica.
+func applyMigration(conn *sql.DB, m migration) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range splitStatements(m.SQL) {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(rebind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.Version, m.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitStatements separa un fichero en sentencias por ';', ya que el
+// driver de MySQL no admite varias sentencias en un mismo Exec. Se quitan
+// las líneas de comentario "--". No contempla ';' dentro de literales.
+func splitStatements(content string) []string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+
+	var stmts []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS solicitudes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	nombre VARCHAR(255) NOT NULL,
+	telefono VARCHAR(255) NOT NULL,
+	servicio VARCHAR(255) NOT NULL,
+	fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
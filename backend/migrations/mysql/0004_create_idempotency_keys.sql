@@ -0,0 +1,8 @@
+-- Claves Idempotency-Key ya procesadas por /submit-service
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	idempotency_key VARCHAR(255) PRIMARY KEY,
+	request_hash CHAR(64) NOT NULL,
+	solicitud_id INT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	INDEX idx_idempotency_keys_created_at (created_at)
+);
@@ -0,0 +1,2 @@
+-- Correo de contacto opcional
+ALTER TABLE solicitudes ADD COLUMN email VARCHAR(255) NULL DEFAULT NULL;
@@ -0,0 +1,2 @@
+-- Estado de la solicitud: nuevo, contactado o cerrado
+ALTER TABLE solicitudes ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'nuevo';
@@ -0,0 +1,2 @@
+-- Técnico asignado; NULL si nadie la tiene
+ALTER TABLE solicitudes ADD COLUMN assigned_to VARCHAR(255) NULL DEFAULT NULL;
@@ -0,0 +1,2 @@
+-- Veces que se recibió la misma solicitud dentro de DEDUP_WINDOW
+ALTER TABLE solicitudes ADD COLUMN submission_count INT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+-- Borrado lógico de solicitudes
+ALTER TABLE solicitudes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP NULL DEFAULT NULL;
@@ -0,0 +1,9 @@
+-- Registro de las acciones de administración sobre las solicitudes
+CREATE TABLE IF NOT EXISTS audit_log (
+	id SERIAL PRIMARY KEY,
+	action VARCHAR(64) NOT NULL,
+	target_id INT NOT NULL,
+	actor VARCHAR(255) NOT NULL,
+	detail TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Origen de cada solicitud, para analizar fraudes. Solo lo ve el admin.
+ALTER TABLE solicitudes ADD COLUMN IF NOT EXISTS ip_address VARCHAR(45) NULL DEFAULT NULL;
+ALTER TABLE solicitudes ADD COLUMN IF NOT EXISTS user_agent VARCHAR(512) NULL DEFAULT NULL;
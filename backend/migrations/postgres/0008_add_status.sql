@@ -0,0 +1,2 @@
+-- Estado de la solicitud: nuevo, contactado o cerrado
+ALTER TABLE solicitudes ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'nuevo';
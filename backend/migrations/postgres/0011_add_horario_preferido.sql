@@ -0,0 +1,2 @@
+-- Franja en la que el cliente prefiere que le llamen: mañana, tarde o noche
+ALTER TABLE solicitudes ADD COLUMN IF NOT EXISTS horario_preferido VARCHAR(16) NULL;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS solicitudes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre TEXT NOT NULL,
+	telefono TEXT NOT NULL,
+	servicio TEXT NOT NULL,
+	fecha_creacion TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,9 @@
+-- Registro de las acciones de administración sobre las solicitudes
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	action TEXT NOT NULL,
+	target_id INTEGER NOT NULL,
+	actor TEXT NOT NULL,
+	detail TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Descripción del problema escrita por el cliente
+ALTER TABLE solicitudes ADD COLUMN mensaje TEXT NULL;
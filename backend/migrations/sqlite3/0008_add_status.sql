@@ -0,0 +1,2 @@
+-- Estado de la solicitud: nuevo, contactado o cerrado
+ALTER TABLE solicitudes ADD COLUMN status TEXT NOT NULL DEFAULT 'nuevo';
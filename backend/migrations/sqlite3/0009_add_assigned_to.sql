@@ -0,0 +1,2 @@
+-- Técnico asignado; NULL si nadie la tiene
+ALTER TABLE solicitudes ADD COLUMN assigned_to TEXT NULL DEFAULT NULL;
@@ -0,0 +1,5 @@
+-- Huella de nombre, teléfono y servicio de la última solicitud recibida
+-- dentro de DEDUP_WINDOW; el índice único hace que dos envíos simultáneos
+-- no creen dos solicitudes
+ALTER TABLE solicitudes ADD COLUMN dedup_key TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_solicitudes_dedup_key ON solicitudes (dedup_key);
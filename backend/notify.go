@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Tiempo máximo de cada notificación, reintentos incluidos
+const notifyTimeout = 30 * time.Second
+
+// notifier avisa de una solicitud nueva a un sistema externo.
+type notifier interface {
+	Name() string
+	Notify(ctx context.Context, s SolicitudGuardada) error
+}
+
+// notifiers son los avisos configurados; Config.apply los crea.
+var notifiers []notifier
+
+// notifyWG permite esperar a las notificaciones en curso al apagar.
+var notifyWG sync.WaitGroup
+
+// notifyNuevaSolicitud lanza en segundo plano todas las notificaciones de
+// una solicitud recién creada. Los fallos solo se registran: nunca afectan
+// a la respuesta al cliente.
+func notifyNuevaSolicitud(log *slog.Logger, s SolicitudGuardada) {
+	for _, n := range notifiersFor(s.Servicio) {
+		notifyWG.Add(1)
+		go func() {
+			defer notifyWG.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			if err := n.Notify(ctx, s); err != nil {
+				log.Error("Error al notificar la solicitud", "notifier", n.Name(), "id", s.ID, "error", err)
+				return
+			}
+			log.Info("Solicitud notificada", "notifier", n.Name(), "id", s.ID)
+		}()
+	}
+}
+
+// waitNotifications espera a que terminen las notificaciones en curso o a
+// que venza ctx.
+func waitNotifications(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		notifyWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Warn("Apagado con notificaciones pendientes")
+	}
+}
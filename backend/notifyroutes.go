@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// notifyRoute son los destinos de los avisos de un servicio. Cada lista
+// que se indique sustituye a la general (SMTP_TO o WEBHOOK_URL); la que se
+// omita usa la general.
+type notifyRoute struct {
+	Emails   []string `json:"emails"`
+	Webhooks []string `json:"webhooks"`
+}
+
+// serviceNotifiers son los avisos de los servicios con destinos propios
+// (NOTIFY_ROUTES); los demás servicios usan notifiers. Config.apply los
+// crea.
+var serviceNotifiers map[string][]notifier
+
+// notifiersFor devuelve los avisos que corresponden a servicio. El
+// servicio se busca sin distinguir mayúsculas, como al validarlo.
+func notifiersFor(servicio string) []notifier {
+	if ns, ok := lookupServicio(serviceNotifiers, servicio); ok {
+		return ns
+	}
+	return notifiers
+}
+
+// loadNotifyRoutes lee los destinos por servicio del JSON de NOTIFY_ROUTES
+// o, si no está definida, del fichero NOTIFY_ROUTES_FILE. Las claves son
+// nombres de servicio y se comparan con services sin distinguir
+// mayúsculas; el mapa devuelto usa el nombre tal como está configurado.
+func loadNotifyRoutes(services []string) (map[string]notifyRoute, error) {
+	data := []byte(strings.TrimSpace(os.Getenv("NOTIFY_ROUTES")))
+	source := "NOTIFY_ROUTES"
+	if len(data) == 0 {
+		path := strings.TrimSpace(os.Getenv("NOTIFY_ROUTES_FILE"))
+		if path == "" {
+			return nil, nil
+		}
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("No se pudo leer NOTIFY_ROUTES_FILE: %w", err)
+		}
+		source = "NOTIFY_ROUTES_FILE"
+	}
+	return parseNotifyRoutes(source, data, services)
+}
+
+func parseNotifyRoutes(source string, data []byte, services []string) (map[string]notifyRoute, error) {
+	var raw map[string]notifyRoute
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%s no es un JSON válido: %w", source, err)
+	}
+
+	routes := make(map[string]notifyRoute, len(raw))
+	for name, route := range raw {
+		servicio, ok := matchServicio(services, name)
+		if !ok {
+			return nil, fmt.Errorf("%s contiene un servicio desconocido: %q", source, name)
+		}
+		if _, dup := routes[servicio]; dup {
+			return nil, fmt.Errorf("%s repite el servicio %q", source, servicio)
+		}
+		if route.Emails != nil && len(route.Emails) == 0 || route.Webhooks != nil && len(route.Webhooks) == 0 {
+			return nil, fmt.Errorf("%s: el servicio %q tiene una lista de destinos vacía", source, servicio)
+		}
+		for _, email := range route.Emails {
+			if !validEmail(email) {
+				return nil, fmt.Errorf("%s: el servicio %q tiene un correo no válido: %q", source, servicio, email)
+			}
+		}
+		for _, u := range route.Webhooks {
+			if !validHTTPURL(u) {
+				return nil, fmt.Errorf("%s: el servicio %q tiene un webhook que no es una URL http o https: %q", source, servicio, u)
+			}
+		}
+		routes[servicio] = route
+	}
+	return routes, nil
+}
+
+// buildNotifiers crea los avisos generales y los de cada servicio con
+// destinos propios. El SMS va al cliente, así que es igual en todos.
+func buildNotifiers(c Config) ([]notifier, map[string][]notifier) {
+	var sms notifier
+	if c.SMSEnabled {
+		sms = &smsNotifier{sender: newTwilioClient(c.Twilio)}
+	}
+	build := func(webhooks, emails []string) []notifier {
+		var ns []notifier
+		for _, u := range webhooks {
+			ns = append(ns, newWebhookNotifier(u))
+		}
+		if c.SMTP.Host != "" && len(emails) > 0 {
+			smtp := c.SMTP
+			smtp.To = emails
+			ns = append(ns, newEmailNotifier(smtp))
+		}
+		if sms != nil {
+			ns = append(ns, sms)
+		}
+		return ns
+	}
+
+	var defaultWebhooks []string
+	if c.WebhookURL != "" {
+		defaultWebhooks = []string{c.WebhookURL}
+	}
+	defaults := build(defaultWebhooks, c.SMTP.To)
+
+	byService := make(map[string][]notifier, len(c.NotifyRoutes))
+	for servicio, route := range c.NotifyRoutes {
+		webhooks, emails := defaultWebhooks, c.SMTP.To
+		if route.Webhooks != nil {
+			webhooks = route.Webhooks
+		}
+		if route.Emails != nil {
+			emails = route.Emails
+		}
+		byService[servicio] = build(webhooks, emails)
+	}
+	return defaults, byService
+}
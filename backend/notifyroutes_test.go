@@ -0,0 +1,85 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// webhookURLs devuelve las URLs de los webhooks de ns.
+func webhookURLs(ns []notifier) []string {
+	var urls []string
+	for _, n := range ns {
+		if w, ok := n.(*webhookNotifier); ok {
+			urls = append(urls, w.url)
+		}
+	}
+	return urls
+}
+
+// emailRecipients devuelve los destinatarios de los correos de ns.
+func emailRecipients(ns []notifier) []string {
+	var to []string
+	for _, n := range ns {
+		if e, ok := n.(*emailNotifier); ok {
+			to = append(to, e.cfg.To...)
+		}
+	}
+	return to
+}
+
+func TestNotifiersForRoutesByService(t *testing.T) {
+	routes, err := parseNotifyRoutes("NOTIFY_ROUTES", []byte(`{
+		"recuperación de datos": {"emails": ["datos@example.com", "jefe@example.com"]},
+		"Mantenimiento de PC": {"webhooks": ["https://hooks.example.com/mant"]}
+	}`), defaultAllowedServices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		WebhookURL:   "https://hooks.example.com/general",
+		SMTP:         smtpConfig{Host: "smtp.example.com", To: []string{"general@example.com"}},
+		NotifyRoutes: routes,
+	}
+	prevDefault, prevByService := notifiers, serviceNotifiers
+	notifiers, serviceNotifiers = buildNotifiers(cfg)
+	t.Cleanup(func() { notifiers, serviceNotifiers = prevDefault, prevByService })
+
+	tests := []struct {
+		servicio string
+		webhooks []string
+		emails   []string
+	}{
+		// Solo cambian los correos; el webhook es el general
+		{"Recuperación de Datos", []string{"https://hooks.example.com/general"}, []string{"datos@example.com", "jefe@example.com"}},
+		// Las mayúsculas no importan
+		{"RECUPERACIÓN DE DATOS", []string{"https://hooks.example.com/general"}, []string{"datos@example.com", "jefe@example.com"}},
+		{" mantenimiento de pc ", []string{"https://hooks.example.com/mant"}, []string{"general@example.com"}},
+		// Sin ruta propia, los generales
+		{"Instalación de Windows", []string{"https://hooks.example.com/general"}, []string{"general@example.com"}},
+		{"Otro servicio", []string{"https://hooks.example.com/general"}, []string{"general@example.com"}},
+	}
+	for _, tt := range tests {
+		ns := notifiersFor(tt.servicio)
+		if got := webhookURLs(ns); !slices.Equal(got, tt.webhooks) {
+			t.Errorf("%q: webhooks = %v, want %v", tt.servicio, got, tt.webhooks)
+		}
+		if got := emailRecipients(ns); !slices.Equal(got, tt.emails) {
+			t.Errorf("%q: emails = %v, want %v", tt.servicio, got, tt.emails)
+		}
+	}
+}
+
+func TestParseNotifyRoutesErrors(t *testing.T) {
+	for _, raw := range []string{
+		`{"Desconocido": {"emails": ["a@example.com"]}}`,
+		`{"Mantenimiento de PC": {"emails": []}}`,
+		`{"Mantenimiento de PC": {"emails": ["no-es-correo"]}}`,
+		`{"Mantenimiento de PC": {"webhooks": ["ftp://example.com"]}}`,
+		`{"Mantenimiento de PC": {"otro": []}}`,
+		`{"Mantenimiento de PC": {}, "mantenimiento de pc": {}}`,
+	} {
+		if _, err := parseNotifyRoutes("NOTIFY_ROUTES", []byte(raw), defaultAllowedServices); err == nil {
+			t.Errorf("parseNotifyRoutes(%s) no devolvió error", raw)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// startTime es el momento en que arrancó el proceso, para el uptime.
+var startTime = time.Now()
+
+// minuteCounter cuenta eventos por minuto durante la última hora, sin
+// consultar la base de datos. Cada hueco guarda el minuto al que
+// corresponde; los de hace más de una hora se reutilizan.
+type minuteCounter struct {
+	mu      sync.Mutex
+	minutes [60]int64
+	counts  [60]int
+}
+
+// solicitudesRecientes cuenta las solicitudes creadas desde el formulario.
+var solicitudesRecientes minuteCounter
+
+func (c *minuteCounter) Inc(now time.Time) {
+	minute := now.Unix() / 60
+	i := minute % int64(len(c.counts))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.minutes[i] != minute {
+		c.minutes[i] = minute
+		c.counts[i] = 0
+	}
+	c.counts[i]++
+}
+
+// LastHour devuelve los eventos de los últimos 60 minutos, el actual
+// incluido.
+func (c *minuteCounter) LastHour(now time.Time) int {
+	minute := now.Unix() / 60
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for i, m := range c.minutes {
+		if minute-m < int64(len(c.counts)) {
+			total += c.counts[i]
+		}
+	}
+	return total
+}
+
+// recordSolicitudCreada anota una solicitud nueva del formulario en la
+// métrica de Prometheus y en el contador de /status.
+func recordSolicitudCreada(servicio string) {
+	solicitudesCreadasTotal.WithLabelValues(servicio).Inc()
+	solicitudesRecientes.Inc(time.Now())
+}
+
+// dbPoolStatus es el resumen de db.Stats() que devuelve /status.
+type dbPoolStatus struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMs     int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+// statusHandler devuelve un resumen del estado del proceso para
+// administración: uptime, solicitudes de la última hora y el pool de
+// conexiones. No consulta la base de datos.
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	stats := db.Stats()
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(struct {
+		Version             string       `json:"version"`
+		StartedAt           time.Time    `json:"started_at"`
+		UptimeSeconds       int64        `json:"uptime_seconds"`
+		SubmissionsLastHour int          `json:"submissions_last_hour"`
+		Maintenance         bool         `json:"maintenance"`
+		DBPool              dbPoolStatus `json:"db_pool"`
+	}{
+		Version:             version,
+		StartedAt:           startTime.UTC().Truncate(time.Second),
+		UptimeSeconds:       int64(now.Sub(startTime).Seconds()),
+		SubmissionsLastHour: solicitudesRecientes.LastHour(now),
+		Maintenance:         maintenanceMode.Load(),
+		DBPool: dbPoolStatus{
+			MaxOpenConnections: stats.MaxOpenConnections,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+			MaxIdleClosed:      stats.MaxIdleClosed,
+			MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+		},
+	})
+}
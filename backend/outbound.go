@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Timeout por defecto de cada llamada a un servicio externo
+// (OUTBOUND_TIMEOUT)
+const defaultOutboundTimeout = 10 * time.Second
+
+// Límites de conexiones del cliente de salida. Con un servicio externo
+// lento, las peticiones esperan conexión en lugar de abrir sin límite.
+const (
+	outboundMaxIdleConns        = 50
+	outboundMaxIdleConnsPerHost = 10
+	outboundMaxConnsPerHost     = 20
+)
+
+// outboundTimeout es el tiempo máximo de una llamada a un servicio externo,
+// HTTP o SMTP. Config.apply lo cambia junto con outboundClient.
+var outboundTimeout = defaultOutboundTimeout
+
+// outboundClient es el cliente HTTP de todas las llamadas a servicios
+// externos (reCAPTCHA, webhooks, Twilio). No se usa http.DefaultClient
+// porque no tiene timeout.
+var outboundClient = newOutboundClient(defaultOutboundTimeout)
+
+func newOutboundClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: min(timeout, 5*time.Second), KeepAlive: 30 * time.Second}
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = min(timeout, 10*time.Second)
+	transport.ResponseHeaderTimeout = timeout
+	transport.MaxIdleConns = outboundMaxIdleConns
+	transport.MaxIdleConnsPerHost = outboundMaxIdleConnsPerHost
+	transport.MaxConnsPerHost = outboundMaxConnsPerHost
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
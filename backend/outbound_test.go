@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// withOutboundTimeout cambia el timeout y el cliente de salida mientras dura
+// el test, como Config.apply.
+func withOutboundTimeout(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	prevTimeout, prevClient := outboundTimeout, outboundClient
+	outboundTimeout, outboundClient = timeout, newOutboundClient(timeout)
+	t.Cleanup(func() { outboundTimeout, outboundClient = prevTimeout, prevClient })
+}
+
+// slowServer responde pasado delay, o cuando el cliente se va.
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Hasta leer el cuerpo, net/http no se entera de que el cliente cerró
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOutboundClientTimesOut(t *testing.T) {
+	srv := slowServer(t, 5*time.Second)
+	client := newOutboundClient(100 * time.Millisecond)
+
+	start := time.Now()
+	resp, err := client.Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("la llamada a un servidor lento no falló")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("la llamada tardó %s, want ~100ms", elapsed)
+	}
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("error = %v, want un timeout", err)
+	}
+}
+
+func TestOutboundClientIsShared(t *testing.T) {
+	withOutboundTimeout(t, time.Second)
+	if c := newWebhookNotifier("https://example.com/hook").client; c != outboundClient {
+		t.Error("el webhook no usa outboundClient")
+	}
+	if c := newTwilioClient(twilioConfig{}).client; c != outboundClient {
+		t.Error("Twilio no usa outboundClient")
+	}
+	if c := newRecaptchaVerifier("secreto", 0.5).client; c != outboundClient {
+		t.Error("reCAPTCHA no usa outboundClient")
+	}
+	if outboundClient == http.DefaultClient || outboundClient.Timeout != time.Second {
+		t.Errorf("outboundClient.Timeout = %s, want 1s", outboundClient.Timeout)
+	}
+}
+
+func TestWebhookGivesUpOnSlowServer(t *testing.T) {
+	withOutboundTimeout(t, 100*time.Millisecond)
+	srv := slowServer(t, 5*time.Second)
+	n := newWebhookNotifier(srv.URL)
+
+	start := time.Now()
+	if _, err := n.post(t.Context(), []byte(`{}`)); err == nil {
+		t.Fatal("el webhook a un servidor lento no falló")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("el webhook tardó %s, want ~100ms", elapsed)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []int
+		retry    webhookRetryConfig
+		wantErr  string
+		attempts int
+	}{
+		{"5xx y luego 2xx", []int{500, 503, 200}, webhookRetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxElapsed: time.Second}, "", 3},
+		{"429 se reintenta", []int{429, 204}, webhookRetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxElapsed: time.Second}, "", 2},
+		{"4xx definitivo", []int{400, 200}, webhookRetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxElapsed: time.Second}, "error definitivo en el intento 1", 1},
+		{"intentos agotados", []int{500, 500, 500, 200}, webhookRetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxElapsed: time.Second}, "intento 3 de 3", 3},
+		// Tras el segundo intento van al menos 50ms y la espera es de 100ms o más:
+		// pasaría de los 150ms
+		{"tiempo máximo", []int{500, 500, 500, 200}, webhookRetryConfig{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxElapsed: 150 * time.Millisecond}, "tiempo máximo de reintentos agotado tras 2 intentos", 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.statuses[calls.Add(1)-1])
+			}))
+			t.Cleanup(srv.Close)
+			logs := captureLogs(t)
+			n := &webhookNotifier{url: srv.URL, client: srv.Client(), retry: tc.retry}
+
+			err := n.Notify(t.Context(), testSolicitud())
+			if tc.wantErr == "" && err != nil {
+				t.Fatalf("Notify = %v", err)
+			}
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("Notify = %v, want %q", err, tc.wantErr)
+			}
+			if got := int(calls.Load()); got != tc.attempts {
+				t.Errorf("intentos = %d, want %d", got, tc.attempts)
+			}
+			if delivered := fmt.Sprintf(`"msg":"Webhook entregado","id":7,"attempts":%d`, tc.attempts); (tc.wantErr == "") != strings.Contains(logs.String(), delivered) {
+				t.Errorf("log de entrega con %d intentos: %s", tc.attempts, logs)
+			}
+		})
+	}
+}
+
+// Un servidor SMTP que acepta la conexión y no dice nada no debe dejar la
+// notificación colgada.
+func TestSendMailTimesOut(t *testing.T) {
+	withOutboundTimeout(t, 100*time.Millisecond)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	start := time.Now()
+	if err := sendMail(ln.Addr().String(), nil, "web@raynertec.com", []string{"oficina@raynertec.com"}, []byte("hola")); err == nil {
+		t.Fatal("sendMail a un servidor mudo no falló")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sendMail tardó %s, want ~100ms", elapsed)
+	}
+	(<-accepted).Close()
+}
+
+func TestLoadConfigOutboundTimeout(t *testing.T) {
+	for value, wantErr := range map[string]bool{"": false, "3s": false, "0s": true, "-1s": true, "rápido": true} {
+		t.Run(value, func(t *testing.T) {
+			vars := map[string]string{}
+			if value != "" {
+				vars["OUTBOUND_TIMEOUT"] = value
+			}
+			setTestEnv(t, vars)
+			cfg, err := LoadConfig()
+			if (err != nil) != wantErr {
+				t.Errorf("LoadConfig = %v, want error %v", err, wantErr)
+			}
+			if value == "" && err == nil && cfg.OutboundTimeout != defaultOutboundTimeout {
+				t.Errorf("OutboundTimeout = %s, want %s", cfg.OutboundTimeout, defaultOutboundTimeout)
+			}
+		})
+	}
+}
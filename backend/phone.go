@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// defaultCountryCode es el prefijo internacional (sin "+") que se añade a
+// los teléfonos nacionales al normalizarlos (DEFAULT_COUNTRY_CODE).
+var defaultCountryCode string
+
+// normalizePhone convierte un teléfono a formato E.164 (+<prefijo><número>).
+// Acepta números internacionales con "+" o "00"; los demás se consideran
+// nacionales: se quita un 0 inicial de marcación y se antepone country
+// (prefijo internacional, con o sin "+").
+func normalizePhone(raw, country string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	international := strings.HasPrefix(raw, "+")
+
+	var digits strings.Builder
+	for _, r := range raw {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	default:
+		country = strings.TrimPrefix(strings.TrimSpace(country), "+")
+		if country == "" {
+			return "", errors.New("el teléfono no tiene prefijo internacional y no hay un país por defecto")
+		}
+		if !isDigits(country) || len(country) > 3 {
+			return "", errors.New("el prefijo de país por defecto no es válido")
+		}
+		number = country + strings.TrimPrefix(number, "0")
+	}
+
+	// E.164 admite como máximo 15 dígitos y ningún prefijo empieza por 0
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return "", errors.New("el teléfono no se puede expresar en formato E.164")
+	}
+	return "+" + number, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
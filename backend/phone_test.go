@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name, raw, country string
+		want               string
+		wantErr            bool
+	}{
+		{"espacios", " 809 555 1234 ", "1", "+18095551234", false},
+		{"guiones", "809-555-1234", "1", "+18095551234", false},
+		{"paréntesis", "(809) 555-1234", "1", "+18095551234", false},
+		{"puntos", "809.555.1234", "1", "+18095551234", false},
+		{"prefijo con +", "+34 600 123 456", "1", "+34600123456", false},
+		{"prefijo con 00", "0034 600 123 456", "1", "+34600123456", false},
+		{"país por defecto con +", "600123456", "+34", "+34600123456", false},
+		{"0 de marcación nacional", "0612 345 678", "33", "+33612345678", false},
+		{"internacional sin país por defecto", "+1 809 555 1234", "", "+18095551234", false},
+		{"nacional sin país por defecto", "809 555 1234", "", "", true},
+		{"país por defecto no numérico", "809 555 1234", "do", "", true},
+		{"país por defecto demasiado largo", "809 555 1234", "1234", "", true},
+		{"vacío", "", "1", "", true},
+		{"demasiado corto", "+12 345", "1", "", true},
+		{"demasiado largo", "+1234567890123456", "1", "", true},
+		{"prefijo que empieza por 0", "+0809 555 1234", "1", "", true},
+		{"sin dígitos", "llámame", "1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizePhone(tt.raw, tt.country)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("normalizePhone(%q, %q) = %q, want error", tt.raw, tt.country, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizePhone(%q, %q) = %q, %v; want %q", tt.raw, tt.country, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Valores por defecto de la purga de solicitudes eliminadas (PURGE_*)
+const (
+	defaultPurgeInterval  = 24 * time.Hour
+	defaultPurgeBatchSize = 500
+)
+
+// purgeConfig configura la purga: cada Interval se borran definitivamente
+// las solicitudes eliminadas hace más de Retention, de BatchSize en
+// BatchSize. Retention 0 la desactiva.
+type purgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+	BatchSize int
+}
+
+// purgeJob borra en segundo plano las solicitudes eliminadas antiguas,
+// que de otro modo se acumulan para siempre.
+type purgeJob struct {
+	cfg    purgeConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startPurgeJob lanza la purga: una pasada al arrancar y otra cada
+// Interval.
+func startPurgeJob(cfg purgeConfig) *purgeJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &purgeJob{cfg: cfg, cancel: cancel, done: make(chan struct{})}
+	go j.run(ctx)
+	return j
+}
+
+func (j *purgeJob) run(ctx context.Context) {
+	defer close(j.done)
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		j.purge(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop detiene la purga y espera a que termine el lote en curso, o a que
+// venza ctx.
+func (j *purgeJob) Stop(ctx context.Context) {
+	j.cancel()
+	select {
+	case <-j.done:
+	case <-ctx.Done():
+		logger.Warn("Apagado con la purga de solicitudes en curso")
+	}
+}
+
+func (j *purgeJob) purge(ctx context.Context) {
+	before := time.Now().UTC().Add(-j.cfg.Retention)
+	purged, err := purgeDeletedSolicitudes(ctx, before, j.cfg.BatchSize)
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Error al purgar las solicitudes eliminadas", "purged", purged, "error", err)
+	}
+	if purged > 0 {
+		responseCache.invalidate()
+	}
+	if err != nil {
+		return
+	}
+	logger.Info("Purga de solicitudes eliminadas", "purged", purged, "deleted_before", formatTimestamp(before))
+}
+
+// purgeDeletedSolicitudes borra definitivamente las solicitudes eliminadas
+// antes de before y devuelve cuántas borró. Va por lotes de batchSize, cada
+// uno en su propia sentencia, para no bloquear la tabla mucho tiempo. Los
+// ids se leen antes de borrar porque DELETE ... LIMIT solo existe en MySQL.
+func purgeDeletedSolicitudes(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	var purged int64
+	for ctx.Err() == nil {
+		n, err := purgeBatch(ctx, before, batchSize)
+		purged += n
+		if err != nil || n < int64(batchSize) {
+			return purged, err
+		}
+	}
+	return purged, ctx.Err()
+}
+
+func purgeBatch(ctx context.Context, before time.Time, batchSize int) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbQueryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT id FROM solicitudes WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY id LIMIT ?`), before, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	var ids []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+
+	// Se repite la condición por si alguna se restauró entre medias
+	result, err := db.ExecContext(ctx, rebind(`DELETE FROM solicitudes WHERE id IN (`+inPlaceholders(len(ids))+`) AND deleted_at IS NOT NULL AND deleted_at < ?`), append(ids, before)...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
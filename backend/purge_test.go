@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// seedDeleted guarda una solicitud eliminada hace ago.
+func seedDeleted(t *testing.T, ago time.Duration) int64 {
+	t.Helper()
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	if _, err := db.Exec(`UPDATE solicitudes SET deleted_at = ? WHERE id = ?`, time.Now().UTC().Add(-ago).Format(time.DateTime), id); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func remainingIDs(t *testing.T) []int64 {
+	t.Helper()
+	rows, err := db.Query(`SELECT id FROM solicitudes ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		rows.Scan(&id)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPurgeDeletedSolicitudes(t *testing.T) {
+	openTestDB(t)
+	for range 5 {
+		seedDeleted(t, 48*time.Hour)
+	}
+	recent := seedDeleted(t, time.Hour)
+	alive := seedSolicitud(t, Solicitud{Nombre: "Luis", Telefono: "8095552222", Servicio: "Mantenimiento de PC"})
+
+	// Lotes de 2: hacen falta tres pasadas, la última incompleta
+	purged, err := purgeDeletedSolicitudes(context.Background(), time.Now().UTC().Add(-24*time.Hour), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if purged != 5 {
+		t.Errorf("purgadas = %d, want 5", purged)
+	}
+	if got, want := fmt.Sprint(remainingIDs(t)), fmt.Sprint([]int64{recent, alive}); got != want {
+		t.Errorf("quedan %s, want %s", got, want)
+	}
+
+	// Una segunda pasada no encuentra nada
+	if purged, err := purgeDeletedSolicitudes(context.Background(), time.Now().UTC().Add(-24*time.Hour), 2); purged != 0 || err != nil {
+		t.Errorf("segunda pasada = %d, %v", purged, err)
+	}
+}
+
+func TestPurgeKeepsRestoredSolicitudes(t *testing.T) {
+	openTestDB(t)
+	id := seedDeleted(t, 48*time.Hour)
+	wantStatus(t, serveAPI(t, "POST", fmt.Sprintf("/v1/solicitudes/%d/restore", id), ""), http.StatusOK)
+
+	if purged, err := purgeDeletedSolicitudes(context.Background(), time.Now().UTC(), 10); purged != 0 || err != nil {
+		t.Errorf("purga = %d, %v; want 0 tras restaurar", purged, err)
+	}
+}
+
+func TestPurgeDeletedSolicitudesCanceled(t *testing.T) {
+	openTestDB(t)
+	seedDeleted(t, 48*time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := purgeDeletedSolicitudes(ctx, time.Now().UTC(), 10); err == nil {
+		t.Error("la purga con el contexto cancelado no devolvió error")
+	}
+	if n := countSolicitudes(t); n != 1 {
+		t.Errorf("solicitudes = %d, want 1", n)
+	}
+}
+
+func TestPurgeJobRunsAtStartAndStops(t *testing.T) {
+	openTestDB(t)
+	old := seedDeleted(t, 48*time.Hour)
+	recent := seedDeleted(t, time.Hour)
+
+	job := startPurgeJob(purgeConfig{Retention: 24 * time.Hour, Interval: time.Hour, BatchSize: 10})
+	deadline := time.Now().Add(2 * time.Second)
+	for countSolicitudes(t) != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	start := time.Now()
+	job.Stop(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Stop tardó %s", elapsed)
+	}
+	select {
+	case <-job.done:
+	default:
+		t.Error("la purga sigue en marcha tras Stop")
+	}
+	if got := remainingIDs(t); len(got) != 1 || got[0] != recent {
+		t.Errorf("quedan %v, want solo %d (se purga %d)", got, recent, old)
+	}
+}
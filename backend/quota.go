@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errDailyQuotaReached indica que el servicio ya tiene hoy todas las
+// solicitudes que admite su cupo.
+var errDailyQuotaReached = errors.New("cupo diario alcanzado")
+
+// dailyQuotas es el máximo de solicitudes al día (UTC) de cada servicio
+// (SERVICE_DAILY_QUOTAS). Los servicios que no aparecen no tienen límite.
+var dailyQuotas map[string]int
+
+// parseDailyQuotas lee el JSON de SERVICE_DAILY_QUOTAS, p. ej.
+// {"Recuperación de Datos": 10}. Las claves se buscan en services como en
+// NOTIFY_ROUTES.
+func parseDailyQuotas(raw string, services []string) (map[string]int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var values map[string]int
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("SERVICE_DAILY_QUOTAS no es un JSON válido: %w", err)
+	}
+	quotas := make(map[string]int, len(values))
+	for name, quota := range values {
+		servicio, ok := matchServicio(services, name)
+		if !ok {
+			return nil, fmt.Errorf("SERVICE_DAILY_QUOTAS contiene un servicio desconocido: %q", name)
+		}
+		if quota <= 0 {
+			return nil, fmt.Errorf("SERVICE_DAILY_QUOTAS: el cupo de %q debe ser un entero positivo", servicio)
+		}
+		quotas[servicio] = quota
+	}
+	return quotas, nil
+}
+
+// checkDailyQuota devuelve errDailyQuotaReached si servicio ya tiene hoy
+// tantas solicitudes como su cupo, sin distinguir mayúsculas. Se llama
+// dentro de la transacción del INSERT o, en el modo de escritura en lotes,
+// antes de encolar; dos envíos simultáneos, o los que aún esperan en la
+// cola, pueden pasar a la vez el último hueco, así que el cupo puede
+// superarse en alguna solicitud.
+func checkDailyQuota(ctx context.Context, q querier, servicio string) error {
+	quota, ok := lookupServicio(dailyQuotas, servicio)
+	if !ok {
+		return nil
+	}
+	// Se compara en Go: LOWER de SQLite solo cambia las letras ASCII y los
+	// servicios llevan tildes
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	rows, err := q.QueryContext(ctx, rebind(`SELECT servicio, COUNT(*) FROM solicitudes WHERE fecha_creacion >= ? AND deleted_at IS NULL GROUP BY servicio`), today)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var name string
+		var n int
+		if err := rows.Scan(&name, &n); err != nil {
+			return err
+		}
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(servicio)) {
+			count += n
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if count >= quota {
+		return errDailyQuotaReached
+	}
+	return nil
+}
+
+// writeDailyQuotaReached responde 429 con Retry-After hasta la medianoche
+// UTC, cuando se reinicia el cupo.
+func writeDailyQuotaReached(w http.ResponseWriter, r *http.Request, servicio string) {
+	now := time.Now().UTC()
+	untilMidnight := now.Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+	quota, _ := lookupServicio(dailyQuotas, servicio)
+	requestLogger(r).Warn("Cupo diario alcanzado, se rechaza la solicitud", "servicio", servicio, "quota", quota, "status", http.StatusTooManyRequests)
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(untilMidnight.Seconds()))))
+	writeError(w, http.StatusTooManyRequests, codeQuotaExceeded, "Cupo diario alcanzado para este servicio")
+}
+
+// checkBufferedQuota comprueba el cupo antes de encolar una solicitud en el
+// modo de escritura en lotes y, si está lleno, responde 429 y devuelve
+// false. Si la base de datos no responde se acepta: la cola ya está pensada
+// para no depender de ella.
+func checkBufferedQuota(w http.ResponseWriter, r *http.Request, servicio string) bool {
+	ctx, cancel := dbContext(r)
+	defer cancel()
+	err := checkDailyQuota(ctx, db, servicio)
+	if errors.Is(err, errDailyQuotaReached) {
+		writeDailyQuotaReached(w, r, servicio)
+		return false
+	}
+	if err != nil {
+		requestLogger(r).Warn("No se pudo comprobar el cupo diario, se acepta la solicitud", "servicio", servicio, "error", err)
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// withDailyQuotas fija dailyQuotas y allowedServices mientras dura el test.
+func withDailyQuotas(t *testing.T, services []string, quotas map[string]int) {
+	t.Helper()
+	prevServices, prevQuotas := allowedServices, dailyQuotas
+	allowedServices, dailyQuotas = services, quotas
+	t.Cleanup(func() { allowedServices, dailyQuotas = prevServices, prevQuotas })
+}
+
+func TestDailyQuotaRejectsWhenFull(t *testing.T) {
+	openTestDB(t)
+	withDailyQuotas(t, defaultAllowedServices, map[string]int{"Recuperación de Datos": 2})
+
+	for i := range 2 {
+		rec := submit(t, solicitudBody(fmt.Sprintf("Cliente %d", i), fmt.Sprintf("809555000%d", i), "Recuperación de Datos"))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("solicitud %d: status = %d, body = %s", i, rec.Code, rec.Body)
+		}
+	}
+
+	rec := submit(t, solicitudBody("Cliente 3", "8095550009", "recuperación de datos"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429 (body %s)", rec.Code, rec.Body)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codeQuotaExceeded || apiErr.Message != "Cupo diario alcanzado para este servicio" {
+		t.Errorf("error = %+v", apiErr)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("falta Retry-After")
+	}
+
+	// Los servicios sin cupo no se ven afectados
+	if rec := submit(t, solicitudBody("Cliente 4", "8095550010", "Mantenimiento de PC")); rec.Code != http.StatusCreated {
+		t.Errorf("servicio sin cupo: status = %d", rec.Code)
+	}
+}
+
+func TestDailyQuotaIgnoresCaseWithAnyService(t *testing.T) {
+	openTestDB(t)
+	// Con ALLOWED_SERVICES=* el servicio se guarda tal como se escribe
+	withDailyQuotas(t, nil, map[string]int{"Diseño Web": 1})
+
+	if rec := submit(t, solicitudBody("Ana", "8095551111", "diseño web")); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+	if rec := submit(t, solicitudBody("Luis", "8095552222", "DISEÑO WEB")); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+}
+
+func TestDailyQuotaWithWriteBuffer(t *testing.T) {
+	openTestDB(t)
+	withDailyQuotas(t, defaultAllowedServices, map[string]int{"Recuperación de Datos": 1})
+	if rec := submit(t, solicitudBody("Ana", "8095551111", "Recuperación de Datos")); rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body)
+	}
+
+	prev := solicitudBuffer
+	solicitudBuffer = newWriteBuffer(writeBufferConfig{Size: 10, BatchSize: 10})
+	t.Cleanup(func() { solicitudBuffer = prev })
+
+	if rec := submit(t, solicitudBody("Luis", "8095552222", "Recuperación de Datos")); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want 429", rec.Code)
+	}
+	if n := solicitudBuffer.Len(); n != 0 {
+		t.Errorf("se encolaron %d solicitudes por encima del cupo", n)
+	}
+	if rec := submit(t, solicitudBody("Eva", "8095553333", "Mantenimiento de PC")); rec.Code != http.StatusAccepted {
+		t.Errorf("servicio sin cupo: status = %d", rec.Code)
+	}
+}
+
+func TestParseDailyQuotas(t *testing.T) {
+	quotas, err := parseDailyQuotas(`{"recuperación de datos": 3}`, defaultAllowedServices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quotas["Recuperación de Datos"] != 3 {
+		t.Errorf("quotas = %v, want la clave con el nombre configurado", quotas)
+	}
+	for _, raw := range []string{`{"Otro": 1}`, `{"Recuperación de Datos": 0}`, `no es json`} {
+		if _, err := parseDailyQuotas(raw, defaultAllowedServices); err == nil {
+			t.Errorf("parseDailyQuotas(%s) no devolvió error", raw)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Límite por defecto de /submit-service: 5 peticiones por minuto por IP.
+const (
+	defaultRateLimitPerMinute = 5
+	defaultRateLimitBurst     = 5
+)
+
+// Los buckets que llevan este tiempo sin usarse se eliminan del mapa.
+const rateLimitIdleTTL = 10 * time.Minute
+
+// ipRateLimiter mantiene un token bucket por IP de cliente.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*rateLimitClient
+	lastSweep time.Time
+}
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newIPRateLimiter crea un limitador que permite perMinute peticiones por
+// minuto y IP, con ráfagas de hasta burst.
+func newIPRateLimiter(perMinute float64, burst int) *ipRateLimiter {
+	return &ipRateLimiter{
+		limit:   rate.Limit(perMinute / 60),
+		burst:   burst,
+		clients: make(map[string]*rateLimitClient),
+	}
+}
+
+// reserve consume un token para ip. Si no hay tokens disponibles devuelve
+// false y el tiempo que el cliente debe esperar.
+func (l *ipRateLimiter) reserve(ip string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for key, c := range l.clients {
+			if now.Sub(c.lastSeen) > rateLimitIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[ip]
+	if !ok {
+		c = &rateLimitClient{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = c
+	}
+	c.lastSeen = now
+
+	res := c.limiter.ReserveN(now, 1)
+	if !res.OK() {
+		return false, rateLimitIdleTTL
+	}
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// middleware rechaza con 429 las peticiones que superan el límite de su IP.
+func (l *ipRateLimiter) middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if ok, retryAfter := l.reserve(ip); !ok {
+			requestLogger(r).Warn("Límite de peticiones superado", "ip", ip, "status", http.StatusTooManyRequests)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, codeRateLimited, "Demasiadas solicitudes")
+			return
+		}
+		next(w, r)
+	}
+}
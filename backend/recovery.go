@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"runtime/debug"
+)
+
+// withRecovery captura los panics de los handlers, registra la traza con el
+// request_id y responde 500 en lugar de cortar la conexión.
+func withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			// ErrAbortHandler es la forma de net/http de abortar una respuesta
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+			requestLogger(r).Error("Panic en el handler", "panic", err, "stack", string(debug.Stack()))
+			if rec.wroteHeader {
+				// Ya se envió parte de la respuesta: no se puede cambiar el estado
+				return
+			}
+			writeError(w, http.StatusInternalServerError, codeInternal, "Error interno del servidor")
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
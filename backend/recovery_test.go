@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecoveryReturns500(t *testing.T) {
+	var logs bytes.Buffer
+	prev := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { logger = prev })
+
+	// Un servidor real: sin recover, el cliente vería la conexión cortada
+	srv := httptest.NewServer(withRequestID(withRequestLogger(withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var s *SolicitudGuardada
+		w.Write([]byte(s.Nombre))
+	})))))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/solicitudes")
+	if err != nil {
+		t.Fatalf("la petición falló: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", resp.StatusCode)
+	}
+	var apiErr APIError
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		t.Fatalf("cuerpo no es JSON: %v (%q)", err, body)
+	}
+	if apiErr.Code != codeInternal {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeInternal)
+	}
+
+	requestID := resp.Header.Get(requestIDHeader)
+	if !strings.Contains(logs.String(), "Panic en el handler") || !strings.Contains(logs.String(), "recovery_test.go") {
+		t.Errorf("el log no tiene el panic con su traza: %s", logs.String())
+	}
+	if requestID == "" || !strings.Contains(logs.String(), requestID) {
+		t.Errorf("el log no tiene el request_id %q", requestID)
+	}
+}
+
+func TestRecoveryAfterPartialResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("a medias")
+	})).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	// El estado ya enviado no se cambia ni se añade otro cuerpo
+	if rec.Code != http.StatusAccepted || rec.Body.Len() != 0 {
+		t.Errorf("status = %d, body = %q", rec.Code, rec.Body)
+	}
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	defer func() {
+		if err := recover(); err != http.ErrAbortHandler {
+			t.Errorf("recover = %v, want http.ErrAbortHandler", err)
+		}
+	}()
+	withRecovery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
@@ -0,0 +1,117 @@
+package main
+
+import "encoding/json"
+
+// Políticas para los campos opcionales vacíos de las solicitudes en las
+// respuestas (RESPONSE_EMPTY_FIELDS)
+const (
+	// emptyFieldsDefault es el formato de siempre: email, mensaje y
+	// horario_preferido van como "" y el resto de opcionales se omiten
+	emptyFieldsDefault = "default"
+	// emptyFieldsNull incluye todos los opcionales, con null si están vacíos
+	emptyFieldsNull = "null"
+	// emptyFieldsOmit omite todos los opcionales vacíos
+	emptyFieldsOmit = "omit"
+)
+
+var emptyFieldsPolicy = emptyFieldsDefault
+
+// solicitudResponse es una solicitud tal como la devuelve la API y la
+// reciben los webhooks. Es independiente de SolicitudGuardada para que
+// cambiar las columnas no cambie el contrato. Los opcionales son punteros:
+// nil es vacío y se escribe como null; MarshalJSON usa solicitudOmitEmpty
+// para omitirlo según emptyFieldsPolicy.
+type solicitudResponse struct {
+	ID               int64   `json:"id"`
+	Nombre           string  `json:"nombre"`
+	Telefono         string  `json:"telefono"`
+	Servicio         string  `json:"servicio"`
+	Email            *string `json:"email"`
+	Mensaje          *string `json:"mensaje"`
+	HorarioPreferido *string `json:"horario_preferido"`
+	Status           string  `json:"status"`
+	AssignedTo       *string `json:"assigned_to"`
+	SubmissionCount  *int    `json:"submission_count"`
+	FechaCreacion    string  `json:"fecha_creacion"`
+	DeletedAt        *string `json:"deleted_at"`
+	IPAddress        *string `json:"ip_address"`
+	UserAgent        *string `json:"user_agent"`
+}
+
+// newSolicitudResponse convierte una solicitud guardada al formato de la
+// API. Para las respuestas públicas se le pasa s.public().
+func newSolicitudResponse(s SolicitudGuardada) solicitudResponse {
+	resp := solicitudResponse{
+		ID:               s.ID,
+		Nombre:           s.Nombre,
+		Telefono:         s.Telefono,
+		Servicio:         s.Servicio,
+		Email:            optionalText(s.Email),
+		Mensaje:          optionalText(s.Mensaje),
+		HorarioPreferido: optionalText(s.HorarioPreferido),
+		Status:           s.Status,
+		AssignedTo:       s.AssignedTo,
+		FechaCreacion:    s.FechaCreacion,
+		DeletedAt:        s.DeletedAt,
+		IPAddress:        optionalText(s.IPAddress),
+		UserAgent:        optionalText(s.UserAgent),
+	}
+	if s.SubmissionCount != 0 {
+		resp.SubmissionCount = &s.SubmissionCount
+	}
+	// En el formato de siempre los textos del formulario van aunque estén
+	// vacíos
+	if emptyFieldsPolicy == emptyFieldsDefault {
+		resp.Email, resp.Mensaje, resp.HorarioPreferido = &s.Email, &s.Mensaje, &s.HorarioPreferido
+	}
+	return resp
+}
+
+// newSolicitudResponses convierte una lista con newSolicitudResponse.
+func newSolicitudResponses(items []SolicitudGuardada) []solicitudResponse {
+	resp := make([]solicitudResponse, len(items))
+	for i, s := range items {
+		resp[i] = newSolicitudResponse(s)
+	}
+	return resp
+}
+
+// optionalText devuelve nil para el texto vacío.
+func optionalText(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// solicitudOmitEmpty es solicitudResponse con omitempty en los opcionales:
+// los punteros nil no se escriben. Tiene que tener los mismos campos, en el
+// mismo orden, para poder convertir uno en otro.
+type solicitudOmitEmpty struct {
+	ID               int64   `json:"id"`
+	Nombre           string  `json:"nombre"`
+	Telefono         string  `json:"telefono"`
+	Servicio         string  `json:"servicio"`
+	Email            *string `json:"email,omitempty"`
+	Mensaje          *string `json:"mensaje,omitempty"`
+	HorarioPreferido *string `json:"horario_preferido,omitempty"`
+	Status           string  `json:"status"`
+	AssignedTo       *string `json:"assigned_to,omitempty"`
+	SubmissionCount  *int    `json:"submission_count,omitempty"`
+	FechaCreacion    string  `json:"fecha_creacion"`
+	DeletedAt        *string `json:"deleted_at,omitempty"`
+	IPAddress        *string `json:"ip_address,omitempty"`
+	UserAgent        *string `json:"user_agent,omitempty"`
+}
+
+// MarshalJSON escribe los opcionales vacíos como null con emptyFieldsPolicy
+// null y los omite con las demás. En la política por defecto
+// newSolicitudResponse ya rellena con "" los que siempre deben aparecer.
+func (r solicitudResponse) MarshalJSON() ([]byte, error) {
+	if emptyFieldsPolicy == emptyFieldsNull {
+		// Sin el método, para que json no vuelva a llamar a MarshalJSON
+		type withNulls solicitudResponse
+		return json.Marshal(withNulls(r))
+	}
+	return json.Marshal(solicitudOmitEmpty(r))
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// withEmptyFieldsPolicy fija emptyFieldsPolicy mientras dura el test.
+func withEmptyFieldsPolicy(t *testing.T, policy string) {
+	t.Helper()
+	prev := emptyFieldsPolicy
+	emptyFieldsPolicy = policy
+	t.Cleanup(func() { emptyFieldsPolicy = prev })
+}
+
+// testSolicitud es una solicitud guardada sin ningún opcional.
+func testSolicitud() SolicitudGuardada {
+	return SolicitudGuardada{
+		ID:            7,
+		Solicitud:     Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"},
+		Status:        "nuevo",
+		FechaCreacion: "2026-03-01T10:30:00Z",
+	}
+}
+
+func TestSolicitudResponseEmptyFieldsPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{emptyFieldsDefault, `{"id":7,"nombre":"Ana","telefono":"+18095551111","servicio":"Mantenimiento de PC","email":"","mensaje":"","horario_preferido":"","status":"nuevo","fecha_creacion":"2026-03-01T10:30:00Z"}`},
+		{emptyFieldsNull, `{"id":7,"nombre":"Ana","telefono":"+18095551111","servicio":"Mantenimiento de PC","email":null,"mensaje":null,"horario_preferido":null,"status":"nuevo","assigned_to":null,"submission_count":null,"fecha_creacion":"2026-03-01T10:30:00Z","deleted_at":null,"ip_address":null,"user_agent":null}`},
+		{emptyFieldsOmit, `{"id":7,"nombre":"Ana","telefono":"+18095551111","servicio":"Mantenimiento de PC","status":"nuevo","fecha_creacion":"2026-03-01T10:30:00Z"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			withEmptyFieldsPolicy(t, tt.policy)
+			got, err := json.Marshal(newSolicitudResponse(testSolicitud()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolicitudResponseFilledFields(t *testing.T) {
+	for _, policy := range []string{emptyFieldsDefault, emptyFieldsNull, emptyFieldsOmit} {
+		withEmptyFieldsPolicy(t, policy)
+		s := testSolicitud()
+		s.Email, s.Mensaje, s.HorarioPreferido = "ana@example.com", "<hola>", "tarde"
+		assignee, deleted := "luis", "2026-03-02T08:00:00Z"
+		s.AssignedTo, s.SubmissionCount, s.DeletedAt, s.IPAddress, s.UserAgent = &assignee, 2, &deleted, "203.0.113.7", "Mozilla"
+
+		got, err := json.Marshal(newSolicitudResponse(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"id":7,"nombre":"Ana","telefono":"+18095551111","servicio":"Mantenimiento de PC","email":"ana@example.com","mensaje":"\u003chola\u003e","horario_preferido":"tarde","status":"nuevo","assigned_to":"luis","submission_count":2,"fecha_creacion":"2026-03-01T10:30:00Z","deleted_at":"2026-03-02T08:00:00Z","ip_address":"203.0.113.7","user_agent":"Mozilla"}`
+		if string(got) != want {
+			t.Errorf("%s:\ngot  %s\nwant %s", policy, got, want)
+		}
+	}
+}
+
+func TestWebhookSendsPublicFields(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	s := testSolicitud()
+	assignee := "luis"
+	s.AssignedTo, s.SubmissionCount, s.IPAddress, s.UserAgent = &assignee, 2, "203.0.113.7", "Mozilla"
+	n := &webhookNotifier{url: srv.URL, client: srv.Client(), retry: webhookRetry}
+	if err := n.Notify(t.Context(), s); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(body), `"nombre":"Ana"`) {
+		t.Fatalf("body = %s", body)
+	}
+	for _, field := range []string{"ip_address", "user_agent", "assigned_to", "submission_count", "203.0.113.7"} {
+		if strings.Contains(string(body), field) {
+			t.Errorf("el webhook envía %q: %s", field, body)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newRouter registra todas las rutas de la API con su método y los
+// middlewares comunes.
+func newRouter(cfg Config) http.Handler {
+	r := chi.NewRouter()
+	r.Use(withRequestID, withLanguage, withRequestLogger, withAccessLog)
+	if cfg.MetricsEnabled {
+		r.Use(metricsMiddleware)
+	}
+	if cfg.OTLPEndpoint != "" {
+		r.Use(tracingMiddleware)
+	}
+	r.Use(gzipMiddleware, corsMiddleware, withRequestTimeout(cfg.Timeouts.Request), withRecovery)
+
+	r.NotFound(notFoundHandler)
+	r.MethodNotAllowed(methodNotAllowedHandler(r))
+
+	// Health checks para Railway y la monitorización; responden aunque la
+	// base de datos no esté lista
+	for _, path := range []string{"/health", "/readyz"} {
+		r.Get(path, healthHandler)
+		r.Head(path, healthHandler)
+	}
+	r.Get("/", welcomeHandler)
+	r.Get("/livez", livezHandler)
+	r.Head("/livez", livezHandler)
+	r.Get("/ping", pingHandler)
+	r.Head("/ping", pingHandler)
+	r.Get("/version", versionHandler)
+	if cfg.MetricsEnabled {
+		r.Method("GET", "/metrics", metricsHandler)
+	}
+
+	submitLimiter := newIPRateLimiter(cfg.RateLimitPerMinute, cfg.RateLimitBurst)
+	r.Group(func(r chi.Router) {
+		r.Use(requireDBReady)
+
+		// --- API ---
+		// Cada versión se monta bajo su prefijo. Las rutas sin prefijo son
+		// alias obsoletos de /v1 mientras los clientes migran.
+		r.Route("/v1", func(r chi.Router) {
+			apiV1Routes(r, submitLimiter)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(deprecatedAlias("/v1"))
+			apiV1Routes(r, submitLimiter)
+		})
+	})
+
+	return r
+}
+
+// notFoundHandler responde 404 en JSON a cualquier ruta no registrada, para
+// que un error al escribir la URL no parezca una respuesta correcta.
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	// requestLogger ya incluye el método y la ruta
+	requestLogger(r).Info("Ruta no encontrada")
+	writeError(w, http.StatusNotFound, codeNotFound, "Ruta no encontrada")
+}
+
+// routeMethods son los métodos que se prueban para construir la cabecera
+// Allow de las respuestas 405.
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// methodNotAllowedHandler responde 405 con la cabecera Allow que pide la
+// RFC 9110, listando los métodos que la ruta sí admite. chi no pasa esa
+// lista a los handlers propios, así que se vuelve a buscar la ruta en
+// routes con cada método.
+func methodNotAllowedHandler(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			if routes.Match(chi.NewRouteContext(), method, r.URL.Path) {
+				allowed = append(allowed, method)
+			}
+		}
+		// corsMiddleware responde a OPTIONS en todas las rutas
+		allowed = append(allowed, "OPTIONS")
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Método no permitido")
+	}
+}
+
+// apiV1Routes registra las rutas de la versión 1 de la API.
+func apiV1Routes(r chi.Router, submitLimiter *ipRateLimiter) {
+	r.With(rejectInMaintenance).Post("/submit-service", submitLimiter.middleware(submitServiceHandler))
+	r.Get("/submit-service/schema", submissionSchemaHandler)
+	r.Get("/services", servicesHandler)
+
+	// Administración, protegida con ADMIN_API_KEY
+	r.Group(func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler { return requireAdmin(next.ServeHTTP) })
+		r.Get("/maintenance", maintenanceHandler)
+		r.Put("/maintenance", setMaintenanceHandler)
+		r.Get("/status", statusHandler)
+
+		// Durante el mantenimiento se rechazan las escrituras; las rutas de
+		// arriba quedan fuera para poder desactivarlo, y no consultan la base
+		// de datos
+		r.Group(func(r chi.Router) {
+			r.Use(rejectInMaintenance)
+			// Las lecturas se cortan si la base de datos falla seguido; las
+			// escrituras siguen intentándolo
+			r.Use(readBreaker.middleware)
+
+			r.Get("/solicitudes", solicitudesHandler)
+			r.Get("/solicitudes/count", solicitudesCountHandler)
+			r.Get("/solicitudes.csv", solicitudesCSVHandler)
+			r.Get("/solicitudes/services-used", servicesUsedHandler)
+			r.Post("/solicitudes/bulk", bulkImportHandler)
+			r.Get("/solicitudes/by-phone", solicitudesByPhoneHandler)
+			r.Delete("/solicitudes/by-phone", deleteSolicitudesByPhoneHandler)
+			r.Get("/solicitudes/{id}", withSolicitudID(getSolicitudHandler))
+			r.Put("/solicitudes/{id}", withSolicitudID(updateSolicitudHandler))
+			r.Delete("/solicitudes/{id}", withSolicitudID(deleteSolicitudHandler))
+			r.Post("/solicitudes/{id}/restore", withSolicitudID(restoreSolicitudHandler))
+			r.Patch("/solicitudes/{id}/status", withSolicitudID(updateStatusHandler))
+			r.Patch("/solicitudes/{id}/assignee", withSolicitudID(updateAssigneeHandler))
+			r.Get("/stats/by-service", statsByServiceHandler)
+			r.Get("/stats/daily", statsDailyHandler)
+			r.Get("/audit", auditHandler)
+		})
+	})
+}
+
+// deprecatedAlias marca las respuestas de una ruta obsoleta con la cabecera
+// Deprecation y un Link a la ruta equivalente bajo prefix.
+func deprecatedAlias(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+prefix+r.URL.Path+`>; rel="successor-version"`)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Mensaje de bienvenida por defecto de la raíz de la API
+const defaultWelcomeMessage = "Bienvenido a la API de servicios. Usa /v1/submit-service para enviar datos."
+
+var (
+	// welcomeMessage es el mensaje de la raíz (WELCOME_MESSAGE). Solo el
+	// mensaje por defecto se traduce.
+	welcomeMessage = defaultWelcomeMessage
+	// docsURL es el enlace a la documentación de la API (DOCS_URL), o "".
+	docsURL string
+)
+
+// welcomeLinks son las rutas principales que se anuncian en la raíz.
+type welcomeLinks struct {
+	Docs     string `json:"docs,omitempty"`
+	Submit   string `json:"submit"`
+	Schema   string `json:"schema"`
+	Services string `json:"services"`
+	Health   string `json:"health"`
+	Version  string `json:"version"`
+}
+
+// welcomeHandler responde en la raíz con el mensaje de bienvenida, en JSON
+// con enlaces a las rutas principales si el cliente lo acepta y en texto
+// plano si no.
+func welcomeHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Accept")
+	message := translate(responseLang(w), welcomeMessage)
+	if acceptsJSON(r.Header.Get("Accept")) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Service string       `json:"service"`
+			Message string       `json:"message"`
+			Links   welcomeLinks `json:"links"`
+		}{
+			Service: "rayner_tec",
+			Message: message,
+			Links: welcomeLinks{
+				Docs:     docsURL,
+				Submit:   "/v1/submit-service",
+				Schema:   "/v1/submit-service/schema",
+				Services: "/v1/services",
+				Health:   "/health",
+				Version:  "/version",
+			},
+		})
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, message+"\n")
+}
+
+// acceptsJSON indica si la cabecera Accept incluye application/json (con
+// q > 0). Los comodines no cuentan: sin pedir JSON se responde texto.
+func acceptsJSON(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "application/json" {
+			continue
+		}
+		if q, ok := params["q"]; ok {
+			v, err := strconv.ParseFloat(q, 64)
+			return err == nil && v > 0
+		}
+		return true
+	}
+	return false
+}
+
+// requireDBReady responde 503 mientras la base de datos no esté lista.
+func requireDBReady(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !dbReady.Load() {
+			writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "El servicio se está iniciando, inténtalo de nuevo en unos segundos")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
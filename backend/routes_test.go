@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodNotAllowedSetsAllow(t *testing.T) {
+	for _, tc := range []struct {
+		method, path, allow string
+	}{
+		{"GET", "/v1/submit-service", "POST, OPTIONS"},
+		{"GET", "/submit-service", "POST, OPTIONS"},
+		{"POST", "/v1/solicitudes/5", "GET, PUT, DELETE, OPTIONS"},
+		{"DELETE", "/v1/solicitudes/5/status", "PATCH, OPTIONS"},
+		{"POST", "/v1/maintenance", "GET, PUT, OPTIONS"},
+		{"PUT", "/health", "GET, HEAD, OPTIONS"},
+	} {
+		rec := serveAPI(t, tc.method, tc.path, "")
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: status = %d, want 405", tc.method, tc.path, rec.Code)
+			continue
+		}
+		if got := rec.Header().Get("Allow"); got != tc.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tc.method, tc.path, got, tc.allow)
+		}
+	}
+}
+
+func TestUnknownRouteIsNotFound(t *testing.T) {
+	var logs bytes.Buffer
+	prev := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { logger = prev })
+
+	for _, path := range []string{"/nonexistent", "/v1/no-existe", "/v1/solicitudes/5/otra"} {
+		rec := serveAPI(t, "GET", path, "")
+		wantStatus(t, rec, http.StatusNotFound)
+		var apiErr APIError
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if apiErr.Code != codeNotFound || apiErr.Message != "Ruta no encontrada" {
+			t.Errorf("%s: error = %+v", path, apiErr)
+		}
+		if rec.Header().Get("Allow") != "" {
+			t.Errorf("%s: Allow = %q en un 404", path, rec.Header().Get("Allow"))
+		}
+		if !strings.Contains(logs.String(), `"path":"`+path+`"`) {
+			t.Errorf("%s: la ruta no aparece en el log", path)
+		}
+	}
+}
+
+func TestWelcomeStillAnswersRoot(t *testing.T) {
+	rec := serveAPI(t, "GET", "/", "")
+	wantStatus(t, rec, http.StatusOK)
+	if !strings.Contains(rec.Body.String(), welcomeMessage) {
+		t.Errorf("body = %q, want el mensaje de bienvenida", rec.Body)
+	}
+}
+
+// withWelcome deja el mensaje y la documentación de cfg, como Config.apply,
+// mientras dura el test.
+func withWelcome(t *testing.T, cfg Config) {
+	t.Helper()
+	prevMessage, prevDocs := welcomeMessage, docsURL
+	welcomeMessage, docsURL = cfg.WelcomeMessage, cfg.DocsURL
+	t.Cleanup(func() { welcomeMessage, docsURL = prevMessage, prevDocs })
+}
+
+// welcomeJSON pide la raíz en JSON y devuelve el mensaje y los enlaces.
+func welcomeJSON(t *testing.T, path string) (string, map[string]string) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	newRouter(Config{}).ServeHTTP(rec, req)
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		Message string            `json:"message"`
+		Links   map[string]string `json:"links"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("cuerpo no es JSON: %v (%s)", err, rec.Body)
+	}
+	return resp.Message, resp.Links
+}
+
+func TestWelcomeMessageFromEnv(t *testing.T) {
+	const custom = "Rayner Tec Santiago: envía tu solicitud a /v1/submit-service"
+	setTestEnv(t, map[string]string{"WELCOME_MESSAGE": "  " + custom + " ", "DOCS_URL": "https://docs.raynertec.com/api"})
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withWelcome(t, cfg)
+
+	message, links := welcomeJSON(t, "/")
+	if message != custom {
+		t.Errorf("message = %q, want %q", message, custom)
+	}
+	if links["docs"] != "https://docs.raynertec.com/api" || links["submit"] != "/v1/submit-service" {
+		t.Errorf("links = %v", links)
+	}
+	// Un mensaje propio no tiene traducción: se devuelve tal cual
+	if message, _ := welcomeJSON(t, "/?lang=en"); message != custom {
+		t.Errorf("en inglés: message = %q, want %q", message, custom)
+	}
+	if rec := serveAPI(t, "GET", "/", ""); strings.TrimSpace(rec.Body.String()) != custom {
+		t.Errorf("texto plano = %q, want %q", rec.Body, custom)
+	}
+}
+
+func TestWelcomeMessageDefault(t *testing.T) {
+	setTestEnv(t, nil)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withWelcome(t, cfg)
+
+	message, links := welcomeJSON(t, "/")
+	if message != defaultWelcomeMessage {
+		t.Errorf("message = %q, want el mensaje por defecto", message)
+	}
+	if _, ok := links["docs"]; ok {
+		t.Errorf("links.docs = %q sin DOCS_URL", links["docs"])
+	}
+	for _, name := range []string{"submit", "schema", "services", "health", "version"} {
+		if links[name] == "" {
+			t.Errorf("falta links.%s", name)
+		}
+	}
+}
+
+func TestLoadConfigRejectsInvalidDocsURL(t *testing.T) {
+	setTestEnv(t, map[string]string{"DOCS_URL": "docs.raynertec.com"})
+	if _, err := LoadConfig(); err == nil || err.Error() != "DOCS_URL debe ser una URL http o https" {
+		t.Errorf("LoadConfig = %v, want error de DOCS_URL", err)
+	}
+}
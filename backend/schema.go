@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// submissionSchema describe con JSON Schema (draft 2020-12) el cuerpo de
+// POST /submit-service. Se construye con las mismas variables que usa
+// validateSolicitud para que no se desvíe de las reglas reales. Los
+// patrones son los de Go (TELEFONO_REGEX, SERVICIO_REGEX); los por defecto
+// también valen en JavaScript con el flag u.
+func submissionSchema() map[string]any {
+	servicio := map[string]any{
+		"type":      "string",
+		"minLength": minServicioLength,
+		"maxLength": maxServicioLength,
+		"pattern":   servicioRegexp.String(),
+	}
+	if allowedServices != nil {
+		servicio["enum"] = allowedServices
+	}
+
+	properties := map[string]any{
+		"nombre": map[string]any{
+			"type":      "string",
+			"minLength": 1,
+			"maxLength": maxFieldLength,
+		},
+		"telefono": map[string]any{
+			"type":        "string",
+			"maxLength":   maxFieldLength,
+			"pattern":     telefonoRegexp.String(),
+			"description": "Se guarda en formato internacional si se puede normalizar",
+		},
+		"servicio": servicio,
+		"email": map[string]any{
+			"type":      "string",
+			"maxLength": maxFieldLength,
+			"anyOf":     []any{map[string]any{"const": ""}, map[string]any{"format": "email"}},
+		},
+		"mensaje": map[string]any{
+			"type":      "string",
+			"maxLength": maxMensajeLength,
+		},
+		"horario_preferido": map[string]any{
+			"type":        "string",
+			"enum":        append([]string{""}, horariosPreferidos...),
+			"description": "Franja en la que prefiere que le llamen; vacío u omitido si le da igual",
+		},
+		honeypotField: map[string]any{
+			"type":        "string",
+			"maxLength":   0,
+			"description": "Campo trampa para bots: debe ir vacío u omitirse",
+		},
+	}
+	required := []string{"nombre", "telefono", "servicio"}
+	if captchaVerifier != nil {
+		properties[captchaTokenField] = map[string]any{
+			"type":        "string",
+			"minLength":   1,
+			"description": "Token de reCAPTCHA v3",
+		}
+		required = append(required, captchaTokenField)
+	}
+
+	return map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Solicitud",
+		"description":          "Cuerpo de POST /v1/submit-service. Los espacios al inicio y al final se recortan antes de validar, y el servicio se compara sin distinguir mayúsculas.",
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// submissionSchemaHandler devuelve el JSON Schema del cuerpo de
+// /submit-service.
+func submissionSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(submissionSchema())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Servicios que ofrecemos actualmente (los mismos que muestra index.html).
+// Se pueden sobrescribir con ALLOWED_SERVICES, separados por comas, o
+// aceptar cualquiera con ALLOWED_SERVICES=*.
+var defaultAllowedServices = []string{
+	"Instalación de Windows",
+	"Mantenimiento de PC",
+	"Recuperación de Datos",
+}
+
+// allowedServices es nil si se acepta cualquier servicio.
+var allowedServices = defaultAllowedServices
+
+// findServicio busca nombre en la lista de servicios permitidos, sin
+// distinguir mayúsculas ni espacios al inicio/final, y devuelve el nombre
+// tal como está configurado.
+func findServicio(nombre string) (string, bool) {
+	return matchServicio(allowedServices, nombre)
+}
+
+// matchServicio busca nombre en services sin distinguir mayúsculas ni
+// espacios al inicio/final. Con services nil vale cualquier nombre.
+func matchServicio(services []string, nombre string) (string, bool) {
+	nombre = strings.TrimSpace(nombre)
+	if services == nil {
+		return nombre, true
+	}
+	for _, s := range services {
+		if strings.EqualFold(s, nombre) {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// lookupServicio busca servicio en un mapa por servicio de la
+// configuración (SERVICE_DAILY_QUOTAS, NOTIFY_ROUTES) sin distinguir
+// mayúsculas ni espacios, como matchServicio. Con ALLOWED_SERVICES=* las
+// solicitudes guardan el servicio tal como se escribió.
+func lookupServicio[T any](m map[string]T, servicio string) (T, bool) {
+	if v, ok := m[servicio]; ok {
+		return v, true
+	}
+	servicio = strings.TrimSpace(servicio)
+	for name, v := range m {
+		if strings.EqualFold(name, servicio) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// servicesHandler devuelve la lista de servicios permitidos para que el
+// frontend pueda construir su desplegable con la misma fuente de verdad.
+func servicesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if body, ok := responseCache.get("services"); ok {
+		w.Write(body.([]byte))
+		return
+	}
+	// Con ALLOWED_SERVICES=* no hay lista que ofrecer
+	services := allowedServices
+	if services == nil {
+		services = []string{}
+	}
+	body, err := json.Marshal(services)
+	if err != nil {
+		requestLogger(r).Error("Error al codificar los servicios", "error", err)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Error interno del servidor")
+		return
+	}
+	body = append(body, '\n')
+	responseCache.set("services", body)
+	w.Write(body)
+}
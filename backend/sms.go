@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// URL base de la API REST de Twilio
+const twilioAPIBase = "https://api.twilio.com/2010-04-01"
+
+// twilioConfig son las credenciales de Twilio (TWILIO_*).
+type twilioConfig struct {
+	AccountSID string
+	AuthToken  string
+	From       string
+}
+
+// smsSender envía un SMS. Lo implementa twilioClient; se puede sustituir
+// por otro proveedor o por un doble en pruebas.
+type smsSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// twilioClient envía SMS con la API REST de Twilio.
+type twilioClient struct {
+	cfg     twilioConfig
+	baseURL string
+	client  *http.Client
+}
+
+func newTwilioClient(cfg twilioConfig) *twilioClient {
+	return &twilioClient{cfg: cfg, baseURL: twilioAPIBase, client: outboundClient}
+}
+
+func (c *twilioClient) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "From": {c.cfg.From}, "Body": {body}}
+	endpoint := c.baseURL + "/Accounts/" + url.PathEscape(c.cfg.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.cfg.AccountSID, c.cfg.AuthToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Twilio devuelve el motivo del error en un JSON con "message"
+	var twErr struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&twErr)
+	return fmt.Errorf("Twilio respondió %s: %s", resp.Status, twErr.Message)
+}
+
+// smsNotifier envía al cliente un SMS confirmando su solicitud.
+type smsNotifier struct {
+	sender smsSender
+}
+
+func (n *smsNotifier) Name() string { return "sms" }
+
+// Notify solo envía a teléfonos en formato E.164; si no se pudo
+// normalizar el teléfono al guardarlo, no se sabe a qué país enviar.
+func (n *smsNotifier) Notify(ctx context.Context, s SolicitudGuardada) error {
+	if !strings.HasPrefix(s.Telefono, "+") {
+		return errors.New("el teléfono no está en formato internacional, no se envía el SMS")
+	}
+	body := fmt.Sprintf("Hola %s, hemos recibido tu solicitud #%d de %s. Te contactaremos pronto.", s.Nombre, s.ID, s.Servicio)
+	return n.sender.SendSMS(ctx, s.Telefono, body)
+}
@@ -0,0 +1,586 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Paginación del listado de solicitudes.
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// SolicitudGuardada es una Solicitud tal como está almacenada en la base de
+// datos, con su id y fecha de creación. DeletedAt solo se informa en las
+// solicitudes eliminadas (borrado lógico). Las fechas van en RFC 3339 UTC.
+// No se serializa directamente: las respuestas pasan por
+// newSolicitudResponse, y las públicas antes por public().
+type SolicitudGuardada struct {
+	ID int64
+	Solicitud
+	Status     string
+	AssignedTo *string
+	// SubmissionCount cuenta los envíos repetidos con DEDUP_STRATEGY=count
+	SubmissionCount int
+	FechaCreacion   string
+	DeletedAt       *string
+	IPAddress       string
+	UserAgent       string
+}
+
+// public devuelve la solicitud sin los datos internos (origen, técnico
+// asignado y envíos repetidos), para responder al cliente que la envió.
+func (s SolicitudGuardada) public() SolicitudGuardada {
+	s.IPAddress, s.UserAgent = "", ""
+	s.AssignedTo = nil
+	s.SubmissionCount = 0
+	return s
+}
+
+// clientInfo es el origen de una solicitud recibida por /submit-service.
+type clientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Longitud máxima del User-Agent que se guarda (VARCHAR(512))
+const maxUserAgentLength = 512
+
+// requestClientInfo obtiene la IP (según clientIP) y el User-Agent de la
+// petición.
+func requestClientInfo(r *http.Request) clientInfo {
+	ua := r.UserAgent()
+	if len(ua) > maxUserAgentLength {
+		ua = strings.ToValidUTF8(ua[:maxUserAgentLength], "")
+	}
+	return clientInfo{IP: clientIP(r), UserAgent: ua}
+}
+
+// solicitudColumns son las columnas que lee scanSolicitud, en su orden.
+const solicitudColumns = "id, nombre, telefono, servicio, email, mensaje, horario_preferido, status, assigned_to, fecha_creacion, deleted_at, ip_address, user_agent, submission_count"
+
+// rowScanner lo cumplen tanto *sql.Row como *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSolicitud lee una fila seleccionada con solicitudColumns.
+func scanSolicitud(row rowScanner) (SolicitudGuardada, error) {
+	var s SolicitudGuardada
+	var createdAt, deletedAt dbTimestamp
+	var email, mensaje, horario, assignedTo, ip, userAgent sql.NullString
+	err := row.Scan(&s.ID, &s.Nombre, &s.Telefono, &s.Servicio, &email, &mensaje, &horario, &s.Status, &assignedTo, &createdAt, &deletedAt, &ip, &userAgent, &s.SubmissionCount)
+	s.Email, s.Mensaje, s.HorarioPreferido = email.String, mensaje.String, horario.String
+	if assignedTo.Valid {
+		s.AssignedTo = &assignedTo.String
+	}
+	s.FechaCreacion = createdAt.String()
+	s.IPAddress, s.UserAgent = ip.String, userAgent.String
+	if deletedAt.Valid {
+		deleted := deletedAt.String()
+		s.DeletedAt = &deleted
+	}
+	return s, err
+}
+
+// listadoSolicitudes es la respuesta paginada de GET /solicitudes.
+type listadoSolicitudes struct {
+	Items  []solicitudResponse `json:"items"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+	Total  int                 `json:"total"`
+}
+
+// listadoCursor es la respuesta de GET /solicitudes?after=. NextCursor es
+// el valor de after para la página siguiente, o null en la última.
+type listadoCursor struct {
+	Items      []solicitudResponse `json:"items"`
+	Limit      int                 `json:"limit"`
+	NextCursor *int64              `json:"next_cursor"`
+}
+
+// solicitudesHandler lista las solicitudes guardadas, de la más reciente a
+// la más antigua, paginadas con ?limit= y ?offset=. Con ?after= pasa al
+// modo cursor de listSolicitudesAfter. En los dos modos responde 304 si el
+// cliente envía la ETag de la versión actual (ver checkNotModified).
+func solicitudesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	limit, offset, err := parsePagination(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+	filtro, err := parseFiltroSolicitudes(query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+	var after int64
+	if query.Has("after") {
+		if after, err = parseCursor(query); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+			return
+		}
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	// Si nada cambió desde la última consulta del panel, 304 sin leer filas
+	version, done := writeIfNotModified(ctx, w, r, filtro, "Error interno del servidor al consultar las solicitudes")
+	if done {
+		return
+	}
+	if query.Has("after") {
+		listSolicitudesAfter(ctx, w, r, filtro, limit, after)
+		return
+	}
+
+	listado := listadoSolicitudes{Items: []solicitudResponse{}, Limit: limit, Offset: offset, Total: int(version.Count)}
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes`+filtro.where()+
+		` ORDER BY fecha_creacion DESC, id DESC LIMIT ? OFFSET ?`), append(filtro.args, limit, offset)...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+			return
+		}
+		listado.Items = append(listado.Items, newSolicitudResponse(s))
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+
+	json.NewEncoder(w).Encode(listado)
+}
+
+// parseCursor lee ?after=, que debe ser un id positivo o estar vacío para
+// pedir la primera página. No se puede combinar con ?offset=.
+func parseCursor(query url.Values) (int64, error) {
+	if query.Has("offset") {
+		return 0, errors.New("Los parámetros 'after' y 'offset' no se pueden usar a la vez")
+	}
+	raw := query.Get("after")
+	if raw == "" {
+		return 0, nil
+	}
+	after, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || after <= 0 {
+		return 0, errors.New("El parámetro 'after' debe ser un id positivo o estar vacío")
+	}
+	return after, nil
+}
+
+// listSolicitudesAfter es el modo cursor del listado: devuelve las
+// solicitudes con id menor que after (todas si after es 0), de la más
+// nueva a la más antigua por id. A diferencia de offset, las solicitudes
+// que se crean mientras se recorre el listado no desplazan las páginas
+// siguientes, y la consulta usa la clave primaria en lugar de saltar filas.
+// No calcula el total.
+func listSolicitudesAfter(ctx context.Context, w http.ResponseWriter, r *http.Request, filtro filtroSolicitudes, limit int, after int64) {
+	if after > 0 {
+		filtro.add("id < ?", after)
+	}
+
+	// Se pide una fila más para saber si hay página siguiente
+	rows, err := db.QueryContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes`+filtro.where()+
+		` ORDER BY id DESC LIMIT ?`), append(filtro.args, limit+1)...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+	defer rows.Close()
+
+	listado := listadoCursor{Items: []solicitudResponse{}, Limit: limit}
+	for rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+			return
+		}
+		listado.Items = append(listado.Items, newSolicitudResponse(s))
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar las solicitudes")
+		return
+	}
+	if len(listado.Items) > limit {
+		listado.Items = listado.Items[:limit]
+		if limit > 0 {
+			next := listado.Items[limit-1].ID
+			listado.NextCursor = &next
+		}
+	}
+
+	json.NewEncoder(w).Encode(listado)
+}
+
+// solicitudesCountHandler devuelve solo el número de solicitudes que
+// cumplen los filtros del listado, sin leer las filas.
+func solicitudesCountHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filtro, err := parseFiltroSolicitudes(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	version, done := writeIfNotModified(ctx, w, r, filtro, "Error interno del servidor al contar las solicitudes")
+	if done {
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]int{"total": int(version.Count)})
+}
+
+// withSolicitudID adapta un handler que recibe el id de la solicitud,
+// leyéndolo del parámetro {id} de la ruta.
+func withSolicitudID(next func(http.ResponseWriter, *http.Request, int64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, codeInvalidParameter, "El id de la solicitud debe ser un entero positivo")
+			return
+		}
+		next(w, r, id)
+	}
+}
+
+func getSolicitudHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	s, err := findSolicitud(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+
+	json.NewEncoder(w).Encode(newSolicitudResponse(s))
+}
+
+// updateSolicitudHandler corrige los datos enviados por el cliente en una
+// solicitud existente. El id y la fecha de creación no se pueden cambiar.
+// El cambio y su entrada de auditoría se guardan en la misma transacción.
+func updateSolicitudHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	solicitud, ok := decodeSolicitud(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		// MySQL informa 0 filas afectadas también cuando los valores no
+		// cambian, así que la existencia se confirma leyendo antes
+		var antes Solicitud
+		var email, mensaje, horario sql.NullString
+		err := tx.QueryRowContext(ctx, rebind(`SELECT nombre, telefono, servicio, email, mensaje, horario_preferido FROM solicitudes WHERE id = ? AND deleted_at IS NULL`), id).
+			Scan(&antes.Nombre, &antes.Telefono, &antes.Servicio, &email, &mensaje, &horario)
+		if err != nil {
+			return err
+		}
+		antes.Email, antes.Mensaje, antes.HorarioPreferido = email.String, mensaje.String, horario.String
+		// La dedup_key era la huella de los datos enviados: una vez editada
+		// la solicitud la suelta, para que un envío con los datos de antes
+		// no se tome por ella
+		_, err = tx.ExecContext(ctx, rebind(`UPDATE solicitudes SET nombre = ?, telefono = ?, servicio = ?, email = ?, mensaje = ?, horario_preferido = ?, dedup_key = NULL WHERE id = ?`),
+			solicitud.Nombre, solicitud.Telefono, solicitud.Servicio, nullString(solicitud.Email), nullString(solicitud.Mensaje), nullString(solicitud.HorarioPreferido), id)
+		if err != nil {
+			return err
+		}
+		detail, err := json.Marshal(map[string]Solicitud{"antes": antes, "despues": solicitud})
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, auditUpdate, id, adminActor(r), string(detail))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al actualizar la solicitud", "id", id)
+		return
+	}
+
+	s, err := findSolicitud(ctx, id)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+
+	responseCache.invalidate()
+	requestLogger(r).Info("Solicitud actualizada", "id", id)
+	json.NewEncoder(w).Encode(newSolicitudResponse(s))
+}
+
+// deleteSolicitudHandler hace un borrado lógico: marca deleted_at y la
+// solicitud deja de aparecer, pero se puede recuperar con restore.
+func deleteSolicitudHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		err := execOne(ctx, tx, `UPDATE solicitudes SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL`, id)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, auditDelete, id, adminActor(r), "")
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al eliminar la solicitud", "id", id)
+		return
+	}
+
+	responseCache.invalidate()
+	requestLogger(r).Info("Solicitud eliminada", "id", id)
+	json.NewEncoder(w).Encode(map[string]string{"message": translate(responseLang(w), "Eliminada")})
+}
+
+// restoreSolicitudHandler recupera una solicitud eliminada y la devuelve.
+func restoreSolicitudHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		err := execOne(ctx, tx, `UPDATE solicitudes SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, auditRestore, id, adminActor(r), "")
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud eliminada no encontrada")
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al restaurar la solicitud", "id", id)
+		return
+	}
+
+	s, err := findSolicitud(ctx, id)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+
+	responseCache.invalidate()
+	requestLogger(r).Info("Solicitud restaurada", "id", id)
+	json.NewEncoder(w).Encode(newSolicitudResponse(s))
+}
+
+// execOne ejecuta una sentencia que debe afectar a una fila; si no afecta a
+// ninguna devuelve sql.ErrNoRows.
+func execOne(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	result, err := tx.ExecContext(ctx, rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// insertSolicitud guarda una solicitud nueva con su origen y devuelve su id.
+// Postgres no implementa LastInsertId, así que allí se usa RETURNING id.
+func insertSolicitud(ctx context.Context, q querier, s Solicitud, client clientInfo) (int64, error) {
+	const insertSQL = `INSERT INTO solicitudes (` + insertColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	args := insertArgs(s, client)
+	if dbDriver == driverPostgres {
+		var id int64
+		err := q.QueryRowContext(ctx, rebind(insertSQL+` RETURNING id`), args...).Scan(&id)
+		return id, err
+	}
+	result, err := q.ExecContext(ctx, insertSQL, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// Columnas que rellena insertSolicitud, en el orden de insertArgs.
+const insertColumns = "nombre, telefono, servicio, email, mensaje, horario_preferido, ip_address, user_agent"
+
+func insertArgs(s Solicitud, client clientInfo) []any {
+	return []any{s.Nombre, s.Telefono, s.Servicio, nullString(s.Email), nullString(s.Mensaje), nullString(s.HorarioPreferido), nullString(client.IP), nullString(client.UserAgent)}
+}
+
+// nullString guarda los textos vacíos como NULL.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// findSolicitud obtiene una solicitud no eliminada por su id. Devuelve
+// sql.ErrNoRows si no existe o está eliminada.
+func findSolicitud(ctx context.Context, id int64) (s SolicitudGuardada, err error) {
+	err = retryOnBadConn(ctx, func() error {
+		row := db.QueryRowContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes WHERE id = ? AND deleted_at IS NULL`), id)
+		s, err = scanSolicitud(row)
+		return err
+	})
+	return s, err
+}
+
+// filtroSolicitudes acumula las condiciones del WHERE del listado junto con
+// sus argumentos, para usarlas siempre como parámetros de la consulta.
+type filtroSolicitudes struct {
+	conditions []string
+	args       []any
+}
+
+func (f *filtroSolicitudes) add(condition string, args ...any) {
+	f.conditions = append(f.conditions, condition)
+	f.args = append(f.args, args...)
+}
+
+// where devuelve la cláusula WHERE (con espacio inicial) o "" si no hay
+// condiciones.
+func (f filtroSolicitudes) where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
+
+// parseFiltroSolicitudes construye el filtro a partir de la query:
+//   - include_deleted: si es true incluye las solicitudes eliminadas.
+//   - servicio: coincidencia exacta sin distinguir mayúsculas.
+//   - q: texto contenido en nombre o servicio, sin distinguir mayúsculas.
+//   - from: fecha (YYYY-MM-DD) desde la que se incluyen solicitudes.
+//   - to: fecha (YYYY-MM-DD) a partir de la cual se excluyen (no incluida).
+func parseFiltroSolicitudes(query url.Values) (filtroSolicitudes, error) {
+	var f filtroSolicitudes
+	includeDeleted := false
+	if raw := query.Get("include_deleted"); raw != "" {
+		var err error
+		if includeDeleted, err = strconv.ParseBool(raw); err != nil {
+			return f, errors.New("El parámetro 'include_deleted' debe ser true o false")
+		}
+	}
+	if !includeDeleted {
+		f.add("deleted_at IS NULL")
+	}
+	if servicio := strings.TrimSpace(query.Get("servicio")); servicio != "" {
+		f.add("LOWER(servicio) = LOWER(?)", servicio)
+	}
+	if status := strings.TrimSpace(query.Get("status")); status != "" {
+		if !validStatus(status) {
+			return f, errors.New("El parámetro 'status' debe ser nuevo, contactado o cerrado")
+		}
+		f.add("status = ?", status)
+	}
+	if assignee := strings.TrimSpace(query.Get("assigned_to")); assignee == unassignedFilter {
+		f.add("assigned_to IS NULL")
+	} else if assignee != "" {
+		f.add("assigned_to = ?", assignee)
+	}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		if utf8.RuneCountInString(q) > maxFieldLength {
+			return f, fmt.Errorf("El parámetro 'q' no puede superar %d caracteres", maxFieldLength)
+		}
+		// '!' como carácter de escape funciona igual en MySQL, Postgres y
+		// SQLite; la barra invertida no
+		pattern := "%" + escapeLike(strings.ToLower(q)) + "%"
+		f.add("(LOWER(nombre) LIKE ? ESCAPE '!' OR LOWER(servicio) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	if raw := query.Get("from"); raw != "" {
+		from, err := parseDate("from", raw)
+		if err != nil {
+			return f, err
+		}
+		f.add("fecha_creacion >= ?", from)
+	}
+	if raw := query.Get("to"); raw != "" {
+		to, err := parseDate("to", raw)
+		if err != nil {
+			return f, err
+		}
+		f.add("fecha_creacion < ?", to)
+	}
+	return f, nil
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literal.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// parseDate interpreta un parámetro con formato YYYY-MM-DD.
+func parseDate(name, raw string) (time.Time, error) {
+	t, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return t, fmt.Errorf("El parámetro '%s' debe tener el formato YYYY-MM-DD", name)
+	}
+	return t, nil
+}
+
+// parsePagination lee ?limit= y ?offset=. Un limit mayor que maxListLimit
+// se ajusta al máximo en lugar de producir un error.
+func parsePagination(query url.Values) (limit, offset int, err error) {
+	limit, err = parseNonNegativeInt(query, "limit", defaultListLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, err = parseNonNegativeInt(query, "offset", 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	return min(limit, maxListLimit), offset, nil
+}
+
+// parseNonNegativeInt lee un parámetro entero no negativo de la query,
+// devolviendo def si no está presente.
+func parseNonNegativeInt(query url.Values, name string, def int) (int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("El parámetro '%s' debe ser un entero no negativo", name)
+	}
+	return n, nil
+}
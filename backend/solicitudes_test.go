@@ -0,0 +1,223 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDeleteSolicitud(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	path := fmt.Sprintf("/v1/solicitudes/%d", id)
+
+	rec := serveAPI(t, "DELETE", path, "")
+	wantStatus(t, rec, http.StatusOK)
+	var resp map[string]string
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp["message"] != "Eliminada" {
+		t.Errorf("body = %s", rec.Body)
+	}
+
+	wantStatus(t, serveAPI(t, "GET", path, ""), http.StatusNotFound)
+	// Ya eliminada, no se puede volver a eliminar
+	wantStatus(t, serveAPI(t, "DELETE", path, ""), http.StatusNotFound)
+}
+
+func TestDeleteSolicitudErrors(t *testing.T) {
+	openTestDB(t)
+	wantStatus(t, serveAPI(t, "DELETE", "/v1/solicitudes/999", ""), http.StatusNotFound)
+	for _, id := range []string{"abc", "0", "-3"} {
+		wantStatus(t, serveAPI(t, "DELETE", "/v1/solicitudes/"+id, ""), http.StatusBadRequest)
+	}
+}
+
+func TestDeleteSolicitudPreflight(t *testing.T) {
+	rec := serveAPI(t, "OPTIONS", "/v1/solicitudes/1", "")
+	wantStatus(t, rec, http.StatusOK)
+}
+
+func TestUpdateSolicitud(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	path := fmt.Sprintf("/v1/solicitudes/%d", id)
+	before := fechaCreacion(t, id)
+
+	rec := serveAPI(t, "PUT", path, solicitudBody("Ana María", "809-555-2222", "Recuperación de Datos"))
+	wantStatus(t, rec, http.StatusOK)
+	var resp struct {
+		ID       int64  `json:"id"`
+		Nombre   string `json:"nombre"`
+		Servicio string `json:"servicio"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	if resp.ID != id || resp.Nombre != "Ana María" || resp.Servicio != "Recuperación de Datos" {
+		t.Errorf("respuesta = %+v", resp)
+	}
+	if got := fechaCreacion(t, id); !got.Equal(before) {
+		t.Errorf("fecha_creacion = %v, want %v", got, before)
+	}
+
+	var action string
+	db.QueryRow(`SELECT action FROM audit_log WHERE target_id = ?`, id).Scan(&action)
+	if action != auditUpdate {
+		t.Errorf("auditoría = %q, want %q", action, auditUpdate)
+	}
+}
+
+func TestUpdateSolicitudErrors(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+
+	wantStatus(t, serveAPI(t, "PUT", "/v1/solicitudes/999", solicitudBody("Ana", "8095551111", "Mantenimiento de PC")), http.StatusNotFound)
+	// Se valida igual que al crear
+	wantStatus(t, serveAPI(t, "PUT", fmt.Sprintf("/v1/solicitudes/%d", id), solicitudBody("", "8095551111", "Mantenimiento de PC")), http.StatusBadRequest)
+	// El id y la fecha no se pueden cambiar
+	wantStatus(t, serveAPI(t, "PUT", fmt.Sprintf("/v1/solicitudes/%d", id), `{"nombre": "Ana", "telefono": "8095551111", "servicio": "Mantenimiento de PC", "fecha_creacion": "2020-01-01"}`), http.StatusBadRequest)
+
+	var nombre string
+	db.QueryRow(`SELECT nombre FROM solicitudes WHERE id = ?`, id).Scan(&nombre)
+	if nombre != "Ana" {
+		t.Errorf("nombre = %q tras peticiones inválidas", nombre)
+	}
+}
+
+// cursorPage pide una página del listado en modo cursor.
+func cursorPage(t *testing.T, query string) ([]int64, *int64) {
+	t.Helper()
+	rec := serveAPI(t, "GET", "/v1/solicitudes?"+query, "")
+	wantStatus(t, rec, http.StatusOK)
+	var resp listadoCursor
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, s := range resp.Items {
+		ids = append(ids, s.ID)
+	}
+	return ids, resp.NextCursor
+}
+
+func TestCursorPaginationWithInsertsMidScroll(t *testing.T) {
+	openTestDB(t)
+	seed := func() int64 {
+		return seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	}
+	var want []int64
+	for range 7 {
+		want = append([]int64{seed()}, want...)
+	}
+
+	var got []int64
+	query := "limit=3&after="
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatalf("el recorrido no termina: %v", got)
+		}
+		ids, next := cursorPage(t, query)
+		got = append(got, ids...)
+		if next == nil {
+			break
+		}
+		if *next != ids[len(ids)-1] {
+			t.Errorf("next_cursor = %d, want el último id de la página (%d)", *next, ids[len(ids)-1])
+		}
+		// Llegan solicitudes nuevas mientras el administrador hace scroll
+		seed()
+		query = fmt.Sprintf("limit=3&after=%d", *next)
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ids recorridos = %v, want %v sin repetir ni saltar", got, want)
+	}
+}
+
+func TestCursorPaginationLastPage(t *testing.T) {
+	openTestDB(t)
+	for range 3 {
+		seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC"})
+	}
+	// Una página justa no anuncia otra vacía
+	if ids, next := cursorPage(t, "limit=3&after="); len(ids) != 3 || next != nil {
+		t.Errorf("página justa: %d solicitudes, next_cursor = %v", len(ids), next)
+	}
+	if ids, next := cursorPage(t, "after=1"); len(ids) != 0 || next != nil {
+		t.Errorf("tras la última: %v, next_cursor = %v", ids, next)
+	}
+}
+
+func TestCursorPaginationErrors(t *testing.T) {
+	openTestDB(t)
+	for query, message := range map[string]string{
+		"after=abc":         "El parámetro 'after' debe ser un id positivo o estar vacío",
+		"after=0":           "El parámetro 'after' debe ser un id positivo o estar vacío",
+		"after=-3":          "El parámetro 'after' debe ser un id positivo o estar vacío",
+		"after=5&offset=10": "Los parámetros 'after' y 'offset' no se pueden usar a la vez",
+		"after=&offset=0":   "Los parámetros 'after' y 'offset' no se pueden usar a la vez",
+	} {
+		rec := serveAPI(t, "GET", "/v1/solicitudes?"+query, "")
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, rec.Code)
+			continue
+		}
+		var apiErr APIError
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if apiErr.Code != codeInvalidParameter || apiErr.Message != message {
+			t.Errorf("%s: error = %+v, want %s", query, apiErr, message)
+		}
+	}
+}
+
+func TestHorarioPreferidoInReadEndpoints(t *testing.T) {
+	openTestDB(t)
+	id := seedSolicitud(t, Solicitud{Nombre: "Ana", Telefono: "8095551111", Servicio: "Mantenimiento de PC", HorarioPreferido: "tarde"})
+	sin := seedSolicitud(t, Solicitud{Nombre: "Luis", Telefono: "8095552222", Servicio: "Mantenimiento de PC"})
+
+	var one struct {
+		HorarioPreferido *string `json:"horario_preferido"`
+	}
+	rec := serveAPI(t, "GET", fmt.Sprintf("/v1/solicitudes/%d", id), "")
+	wantStatus(t, rec, http.StatusOK)
+	json.Unmarshal(rec.Body.Bytes(), &one)
+	if one.HorarioPreferido == nil || *one.HorarioPreferido != "tarde" {
+		t.Errorf("GET /solicitudes/%d: horario_preferido = %v, want tarde", id, one.HorarioPreferido)
+	}
+
+	var list struct {
+		Items []struct {
+			ID               int64   `json:"id"`
+			HorarioPreferido *string `json:"horario_preferido"`
+		} `json:"items"`
+	}
+	rec = serveAPI(t, "GET", "/v1/solicitudes", "")
+	wantStatus(t, rec, http.StatusOK)
+	json.Unmarshal(rec.Body.Bytes(), &list)
+	got := map[int64]string{}
+	for _, item := range list.Items {
+		if item.HorarioPreferido != nil {
+			got[item.ID] = *item.HorarioPreferido
+		}
+	}
+	if got[id] != "tarde" || got[sin] != "" {
+		t.Errorf("listado: horarios = %v", got)
+	}
+
+	rec = serveAPI(t, "GET", "/v1/solicitudes.csv", "")
+	wantStatus(t, rec, http.StatusOK)
+	header, _, _ := strings.Cut(rec.Body.String(), "\n")
+	if !strings.Contains(header, ",horario_preferido,") || !strings.Contains(rec.Body.String(), ",tarde,") {
+		t.Errorf("CSV sin horario_preferido:\n%s", rec.Body)
+	}
+
+	// PUT también lo corrige, normalizado como al enviarlo
+	rec = serveAPI(t, "PUT", fmt.Sprintf("/v1/solicitudes/%d", id), `{"nombre": "Ana", "telefono": "8095551111", "servicio": "Mantenimiento de PC", "horario_preferido": "Noche"}`)
+	wantStatus(t, rec, http.StatusOK)
+	var stored sql.NullString
+	db.QueryRow(`SELECT horario_preferido FROM solicitudes WHERE id = ?`, id).Scan(&stored)
+	if stored.String != "noche" {
+		t.Errorf("tras PUT: horario guardado = %+v, want noche", stored)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package main
+
+// El driver de SQLite usa cgo, así que solo se incluye al compilar con
+// -tags sqlite. El despliegue con MySQL no lo necesita.
+import _ "github.com/mattn/go-sqlite3"
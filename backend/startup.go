@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"net/url"
+	"slices"
+)
+
+// exitOnConfigErrors registra todos los problemas de configuración en una
+// sola línea y termina el proceso.
+func exitOnConfigErrors(err error) {
+	var errs configErrors
+	if !errors.As(err, &errs) {
+		fatal(err.Error())
+	}
+	problems := make([]string, len(errs))
+	for i, e := range errs {
+		problems[i] = e.Error()
+	}
+	fatal("Configuración no válida, corrige las variables de entorno y vuelve a desplegar", "count", len(problems), "problems", problems)
+}
+
+// logStartupReport registra en una sola línea la configuración efectiva,
+// las funciones opcionales activas, la dirección en la que se escucha y si
+// la base de datos conectó. Con dbErr la línea es de error y devuelve dbErr
+// para que main termine. Los secretos no aparecen: solo se indica si están
+// configurados.
+func logStartupReport(cfg Config, addr string, dbErr error) error {
+	db := []any{
+		"driver", cfg.DBDriver,
+		"dsn", redactedDatabaseURL(cfg),
+		"connected", dbErr == nil,
+		"max_open_conns", cfg.DBPool.MaxOpenConns,
+		"max_idle_conns", cfg.DBPool.MaxIdleConns,
+		"conn_max_lifetime", cfg.DBPool.ConnMaxLifetime.String(),
+		"query_timeout", cfg.DBQueryTimeout.String(),
+	}
+	if dbErr != nil {
+		db = append(db, "error", dbErr.Error())
+	}
+
+	listen := []any{"addr", addr, "tls", cfg.UseTLS()}
+	if cfg.UseTLS() {
+		listen = append(listen, "cert_file", cfg.TLSCertFile, "min_version", tls.VersionName(cfg.TLSMinVersion))
+	}
+
+	features := []any{
+		"email", cfg.SMTP.Host != "",
+		"webhook", cfg.WebhookURL != "",
+		"sms", cfg.SMSEnabled,
+		"metrics", cfg.MetricsEnabled,
+		"tracing", cfg.OTLPEndpoint != "",
+		"recaptcha", cfg.RecaptchaSecret != "",
+		"write_buffer", cfg.WriteBuffer.Enabled,
+		"fallback_file", cfg.Fallback.Path != "",
+		"purge", cfg.Purge.Retention > 0,
+		"maintenance", cfg.MaintenanceMode,
+	}
+
+	notifications := []any{}
+	if cfg.SMTP.Host != "" {
+		notifications = append(notifications, "smtp_host", cfg.SMTP.Host, "smtp_to", cfg.SMTP.To)
+	}
+	if cfg.WebhookURL != "" {
+		// Las URLs de los webhooks llevan a menudo un token: solo el host
+		notifications = append(notifications,
+			"webhook_host", urlHost(cfg.WebhookURL),
+			"webhook_max_attempts", cfg.WebhookRetry.MaxAttempts,
+			"webhook_retry_max_time", cfg.WebhookRetry.MaxElapsed.String())
+	}
+	if len(cfg.NotifyRoutes) > 0 {
+		routed := make([]string, 0, len(cfg.NotifyRoutes))
+		for servicio := range cfg.NotifyRoutes {
+			routed = append(routed, servicio)
+		}
+		slices.Sort(routed)
+		notifications = append(notifications, "routed_services", routed)
+	}
+	if cfg.SMSEnabled {
+		notifications = append(notifications, "sms_from", cfg.Twilio.From)
+	}
+
+	submissions := []any{
+		"allowed_services", cfg.AllowedServices,
+		"default_country_code", cfg.DefaultCountryCode,
+		"dedup_window", cfg.DedupWindow.String(),
+		"dedup_strategy", cfg.DedupStrategy,
+		"rate_limit_per_minute", cfg.RateLimitPerMinute,
+		"rate_limit_burst", cfg.RateLimitBurst,
+		"max_body_bytes", cfg.MaxBodyBytes,
+		"mensaje_max_length", cfg.MaxMensajeLength,
+		"empty_fields", cfg.EmptyFieldsPolicy,
+	}
+	if len(cfg.DailyQuotas) > 0 {
+		submissions = append(submissions, "daily_quotas", cfg.DailyQuotas)
+	}
+	if cfg.RecaptchaSecret != "" {
+		submissions = append(submissions, "recaptcha_min_score", cfg.RecaptchaMinScore)
+	}
+
+	jobs := []any{}
+	if cfg.WriteBuffer.Enabled {
+		jobs = append(jobs, "write_buffer_size", cfg.WriteBuffer.Size,
+			"write_buffer_batch_size", cfg.WriteBuffer.BatchSize,
+			"write_buffer_flush_interval", cfg.WriteBuffer.FlushInterval.String())
+	}
+	if cfg.Fallback.Path != "" {
+		jobs = append(jobs, "fallback_file", cfg.Fallback.Path,
+			"fallback_replay_interval", cfg.Fallback.ReplayInterval.String())
+	}
+	if cfg.Purge.Retention > 0 {
+		jobs = append(jobs, "purge_retention", cfg.Purge.Retention.String(),
+			"purge_interval", cfg.Purge.Interval.String(),
+			"purge_batch_size", cfg.Purge.BatchSize)
+	}
+
+	security := []any{
+		"admin_api_key", cfg.AdminAPIKey != "",
+		"allowed_origins", cfg.AllowedOrigins,
+		"cors_allow_credentials", cfg.CORSAllowCredentials,
+		"trusted_proxies", cfg.TrustedProxies,
+	}
+
+	timeouts := []any{
+		"read_header", cfg.Timeouts.ReadHeader.String(),
+		"read", cfg.Timeouts.Read.String(),
+		"write", cfg.Timeouts.Write.String(),
+		"idle", cfg.Timeouts.Idle.String(),
+		"request", cfg.Timeouts.Request.String(),
+		"outbound", cfg.OutboundTimeout.String(),
+	}
+
+	level, msg := slog.LevelInfo, "Arranque completado"
+	if dbErr != nil {
+		level, msg = slog.LevelError, "Arranque fallido: no se pudo conectar con la base de datos"
+	}
+	args := []any{
+		"version", version,
+		"commit", commit,
+		"env_file", cfg.EnvFile,
+		slog.Group("listen", listen...),
+		slog.Group("db", db...),
+		slog.Group("features", features...),
+		slog.Group("notifications", notifications...),
+		slog.Group("submissions", submissions...),
+		slog.Group("jobs", jobs...),
+		slog.Group("security", security...),
+		slog.Group("timeouts", timeouts...),
+	}
+	if cfg.OTLPEndpoint != "" {
+		args = append(args, "otlp_endpoint", urlHost(cfg.OTLPEndpoint))
+	}
+	logger.Log(context.Background(), level, msg, args...)
+	return dbErr
+}
+
+// redactedDatabaseURL es la cadena de conexión sin la contraseña.
+func redactedDatabaseURL(cfg Config) string {
+	dsn, err := normalizeDSN(cfg.DBDriver, cfg.DatabaseURL)
+	if err != nil {
+		return "(no válida)"
+	}
+	return redactDSN(cfg.DBDriver, dsn)
+}
+
+// urlHost devuelve solo el esquema y el host de u, sin ruta ni
+// credenciales.
+func urlHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return "(no válida)"
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogStartupReportReturnsDBError(t *testing.T) {
+	var logs bytes.Buffer
+	prev := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { logger = prev })
+
+	setTestEnv(t, nil)
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sin error de conexión no hay nada que devolver
+	if err := logStartupReport(cfg, "127.0.0.1:8080", nil); err != nil {
+		t.Errorf("logStartupReport = %v, want nil", err)
+	}
+	if !strings.Contains(logs.String(), `"level":"INFO","msg":"Arranque completado"`) {
+		t.Errorf("log = %s", logs.String())
+	}
+
+	// Con él, el informe sale como error y main decide cómo terminar
+	logs.Reset()
+	dbErr := errors.New("connection refused")
+	if err := logStartupReport(cfg, "127.0.0.1:8080", dbErr); !errors.Is(err, dbErr) {
+		t.Errorf("logStartupReport = %v, want %v", err, dbErr)
+	}
+	if !strings.Contains(logs.String(), `"level":"ERROR"`) || !strings.Contains(logs.String(), "connection refused") {
+		t.Errorf("log = %s", logs.String())
+	}
+}
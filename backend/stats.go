@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// serviceStat es el número de solicitudes de un servicio.
+type serviceStat struct {
+	Servicio string `json:"servicio"`
+	Count    int    `json:"count"`
+}
+
+// statsByServiceHandler devuelve cuántas solicitudes hay de cada servicio,
+// de más a menos, opcionalmente dentro de ?from= y ?to=. Como el listado,
+// responde 304 si los datos no cambiaron.
+func statsByServiceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	filtro, err := parseFiltroSolicitudes(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	// Encode ordena los parámetros, así la misma consulta da la misma clave
+	cacheKey := "stats/by-service?" + r.URL.Query().Encode()
+	if cached, ok := responseCache.get(cacheKey); ok {
+		cached := cached.(cachedStats)
+		if !checkNotModified(w, r, cached.version) {
+			json.NewEncoder(w).Encode(cached.stats)
+		}
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	version, done := writeIfNotModified(ctx, w, r, filtro, "Error interno del servidor al calcular las estadísticas")
+	if done {
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT servicio, COUNT(*) AS total FROM solicitudes`+filtro.where()+
+		` GROUP BY servicio ORDER BY total DESC, servicio`), filtro.args...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+		return
+	}
+	defer rows.Close()
+
+	stats := []serviceStat{}
+	for rows.Next() {
+		var st serviceStat
+		if err := rows.Scan(&st.Servicio, &st.Count); err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+			return
+		}
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+		return
+	}
+
+	responseCache.set(cacheKey, cachedStats{stats: stats, version: version})
+	json.NewEncoder(w).Encode(stats)
+}
+
+// cachedStats es lo que guarda statsByServiceHandler en responseCache: la
+// versión permite responder 304 sin consultar la base de datos.
+type cachedStats struct {
+	stats   []serviceStat
+	version listingVersion
+}
+
+// Rango por defecto y máximo de /stats/daily.
+const (
+	defaultDailyStatsDays = 30
+	maxDailyStatsDays     = 366
+)
+
+// dailyStat es el número de solicitudes de un día (YYYY-MM-DD).
+type dailyStat struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
+
+// statsDailyHandler devuelve el número de solicitudes por día entre ?from=
+// (incluido) y ?to= (excluido), con los días sin solicitudes a 0 para que la
+// gráfica no tenga huecos. Por defecto, los últimos 30 días incluyendo hoy.
+func statsDailyHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	from, to, err := parseDailyRange(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidParameter, err.Error())
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	var filtro filtroSolicitudes
+	filtro.add("deleted_at IS NULL")
+	filtro.add("fecha_creacion >= ?", from)
+	filtro.add("fecha_creacion < ?", to)
+	if _, done := writeIfNotModified(ctx, w, r, filtro, "Error interno del servidor al calcular las estadísticas"); done {
+		return
+	}
+
+	rows, err := db.QueryContext(ctx, rebind(`SELECT DATE(fecha_creacion) AS dia, COUNT(*) FROM solicitudes`+filtro.where()+
+		` GROUP BY dia`), filtro.args...)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+		return
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+			return
+		}
+		// Según el driver la fecha llega como "2006-01-02" o como timestamp
+		// completo; los 10 primeros caracteres son el día en ambos casos.
+		if len(day) >= len(time.DateOnly) {
+			day = day[:len(time.DateOnly)]
+		}
+		counts[day] += count
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al calcular las estadísticas")
+		return
+	}
+
+	// Rellenar los días sin solicitudes
+	stats := []dailyStat{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		stats = append(stats, dailyStat{Date: date, Count: counts[date]})
+	}
+
+	json.NewEncoder(w).Encode(stats)
+}
+
+// parseDailyRange lee ?from= y ?to= (YYYY-MM-DD). Sin from, el rango
+// empieza defaultDailyStatsDays días antes de to; sin to, termina hoy
+// (incluido).
+func parseDailyRange(query url.Values) (from, to time.Time, err error) {
+	to = time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if raw := query.Get("to"); raw != "" {
+		if to, err = parseDate("to", raw); err != nil {
+			return from, to, err
+		}
+	}
+	from = to.AddDate(0, 0, -defaultDailyStatsDays)
+	if raw := query.Get("from"); raw != "" {
+		if from, err = parseDate("from", raw); err != nil {
+			return from, to, err
+		}
+	}
+
+	if !from.Before(to) {
+		return from, to, errors.New("El parámetro 'from' debe ser anterior a 'to'")
+	}
+	if to.Sub(from) > maxDailyStatsDays*24*time.Hour {
+		return from, to, fmt.Errorf("El rango no puede superar %d días", maxDailyStatsDays)
+	}
+	return from, to, nil
+}
+
+// servicesUsedHandler devuelve los servicios distintos que aparecen en las
+// solicitudes no eliminadas, ordenados. A diferencia de /services incluye
+// los que ya no están en la lista permitida.
+func servicesUsedHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if servicios, ok := responseCache.get("solicitudes/services-used"); ok {
+		json.NewEncoder(w).Encode(servicios)
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, `SELECT DISTINCT servicio FROM solicitudes WHERE deleted_at IS NULL ORDER BY servicio`)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar los servicios")
+		return
+	}
+	defer rows.Close()
+
+	servicios := []string{}
+	for rows.Next() {
+		var servicio string
+		if err := rows.Scan(&servicio); err != nil {
+			writeDBError(w, r, err, "Error interno del servidor al consultar los servicios")
+			return
+		}
+		servicios = append(servicios, servicio)
+	}
+	if err := rows.Err(); err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar los servicios")
+		return
+	}
+
+	responseCache.set("solicitudes/services-used", servicios)
+	json.NewEncoder(w).Encode(servicios)
+}
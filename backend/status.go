@@ -0,0 +1,109 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+// Estados por los que pasa una solicitud. Las nuevas empiezan en
+// statusNuevo (valor por defecto de la columna).
+const (
+	statusNuevo      = "nuevo"
+	statusContactado = "contactado"
+	statusCerrado    = "cerrado"
+)
+
+// statusTransitions indica a qué estados se puede pasar desde cada uno.
+// Una solicitud cerrada se puede reabrir como contactada, pero ninguna
+// vuelve a nuevo.
+var statusTransitions = map[string][]string{
+	statusNuevo:      {statusContactado, statusCerrado},
+	statusContactado: {statusCerrado},
+	statusCerrado:    {statusContactado},
+}
+
+// validStatus indica si status es uno de los estados conocidos.
+func validStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+// errInvalidTransition indica que el cambio de estado no está permitido.
+var errInvalidTransition = errors.New("transición de estado no permitida")
+
+// updateStatusHandler cambia el estado de una solicitud con un cuerpo
+// {"status": "..."}. El cambio y su entrada de auditoría se guardan en la
+// misma transacción.
+func updateStatusHandler(w http.ResponseWriter, r *http.Request, id int64) {
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := decodeStrict(body, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, decodeErrorResponse(err))
+		return
+	}
+	if !validStatus(req.Status) {
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Message: "Estado no válido: debe ser nuevo, contactado o cerrado",
+			Code:    codeValidation,
+			Field:   "status",
+		})
+		return
+	}
+
+	ctx, cancel := dbContext(r)
+	defer cancel()
+
+	var antes string
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, rebind(`SELECT status FROM solicitudes WHERE id = ? AND deleted_at IS NULL`), id).Scan(&antes)
+		if err != nil || antes == req.Status {
+			return err
+		}
+		if !slices.Contains(statusTransitions[antes], req.Status) {
+			return errInvalidTransition
+		}
+		if err := execOne(ctx, tx, `UPDATE solicitudes SET status = ? WHERE id = ?`, req.Status, id); err != nil {
+			return err
+		}
+		detail, err := json.Marshal(map[string]string{"antes": antes, "despues": req.Status})
+		if err != nil {
+			return err
+		}
+		return insertAudit(ctx, tx, auditStatus, id, adminActor(r), string(detail))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Solicitud no encontrada")
+		return
+	}
+	if errors.Is(err, errInvalidTransition) {
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Message: fmt.Sprintf("No se puede pasar de '%s' a '%s'", antes, req.Status),
+			Code:    codeValidation,
+			Field:   "status",
+		})
+		return
+	}
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al cambiar el estado", "id", id)
+		return
+	}
+
+	s, err := findSolicitud(ctx, id)
+	if err != nil {
+		writeDBError(w, r, err, "Error interno del servidor al consultar la solicitud", "id", id)
+		return
+	}
+
+	responseCache.invalidate()
+	requestLogger(r).Info("Estado de la solicitud cambiado", "id", id, "antes", antes, "despues", req.Status)
+	json.NewEncoder(w).Encode(newSolicitudResponse(s))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	// Los tests usan SQLite aunque el binario se compile sin -tags sqlite
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openTestDB abre una base de datos SQLite vacía con todas las migraciones
+// y la deja en db mientras dura el test. También vacía responseCache.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open(driverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Una sola conexión: SQLite no admite escrituras concurrentes
+	conn.SetMaxOpenConns(1)
+	if err := runMigrations(conn, driverSQLite); err != nil {
+		t.Fatal(err)
+	}
+
+	prevDB, prevDriver := db, dbDriver
+	db, dbDriver = conn, driverSQLite
+	responseCache.invalidate()
+	t.Cleanup(func() {
+		conn.Close()
+		db, dbDriver = prevDB, prevDriver
+		responseCache.invalidate()
+	})
+	return conn
+}
+
+// seedSolicitud guarda una solicitud directamente en db y devuelve su id.
+func seedSolicitud(t *testing.T, s Solicitud) int64 {
+	t.Helper()
+	id, err := insertSolicitud(context.Background(), db, s, clientInfo{IP: "203.0.113.7", UserAgent: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// useUnreachableDB deja en db un MySQL en un puerto cerrado, para simular
+// que la base de datos está caída: las consultas fallan con un error de
+// conexión.
+func useUnreachableDB(t *testing.T) {
+	t.Helper()
+	conn, err := sql.Open(driverMySQL, "user:pass@tcp(127.0.0.1:1)/rayner?timeout=1s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	prevDB, prevDriver := db, dbDriver
+	db, dbDriver = conn, driverMySQL
+	t.Cleanup(func() {
+		conn.Close()
+		db, dbDriver = prevDB, prevDriver
+	})
+}
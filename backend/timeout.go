@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tiempo máximo por defecto de cada petición (REQUEST_TIMEOUT). Debe ser
+// menor que HTTP_WRITE_TIMEOUT para que el cliente reciba el 503.
+const defaultRequestTimeout = 20 * time.Second
+
+// withRequestTimeout corta las peticiones que tardan más de timeout y
+// responde 503. El handler recibe un contexto con ese plazo, así que
+// dbContext y las llamadas externas se cancelan con él. Como
+// http.TimeoutHandler, la respuesta se guarda en memoria hasta que el
+// handler termina; a diferencia de él, el handler ve las cabeceras que ya
+// pusieron los middlewares (X-Request-ID, Content-Language) y el error es
+// un APIError. Con timeout 0 no hace nada.
+//
+// Go no puede parar la goroutine del handler: sigue hasta que vuelve, y lo
+// que escriba después del plazo se descarta. Por eso los handlers deben
+// sacar el contexto de la petición (dbContext(r), r.Context()) para todo lo
+// que pueda bloquearse; context.Background() queda para el trabajo que debe
+// seguir tras responder, como las notificaciones.
+//
+// Las rutas de streamingRoutes quedan fuera: guardarlas en memoria anularía
+// el streaming y las exportaciones grandes recibirían el 503.
+func withRequestTimeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isStreamingRoute(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			tw := &timeoutWriter{header: w.Header().Clone(), status: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					// withRecovery, por dentro, ya atiende los demás panics;
+					// ErrAbortHandler tiene que llegar a net/http
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				dst := w.Header()
+				clear(dst)
+				for k, v := range tw.header {
+					dst[k] = v
+				}
+				w.WriteHeader(tw.status)
+				w.Write(tw.body.Bytes())
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					// El cliente cerró la conexión: no hay a quién responder
+					return
+				}
+				requestLogger(r).Warn("Petición cortada por superar REQUEST_TIMEOUT", "timeout", timeout.String(), "status", http.StatusServiceUnavailable)
+				writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "La petición tardó demasiado, inténtalo de nuevo")
+			}
+		})
+	}
+}
+
+// streamingRoutes son las rutas que escriben la respuesta según la generan.
+// Sin REQUEST_TIMEOUT, las limitan DB_QUERY_TIMEOUT y HTTP_WRITE_TIMEOUT.
+var streamingRoutes = []string{"/solicitudes.csv"}
+
+// isStreamingRoute indica si path es una de streamingRoutes, con o sin el
+// prefijo de versión.
+func isStreamingRoute(path string) bool {
+	for _, route := range streamingRoutes {
+		if strings.HasSuffix(path, route) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeoutWriter guarda la respuesta del handler hasta que termina. Si el
+// plazo vence antes, lo que escriba después se descarta.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(b)
+}
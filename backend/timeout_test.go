@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutSlowHandler(t *testing.T) {
+	finished := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(finished)
+		<-r.Context().Done()
+		w.Write([]byte("tarde"))
+	})
+	h := withRequestTimeout(20 * time.Millisecond)(slow)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/solicitudes", nil))
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+	var apiErr APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("cuerpo no es JSON: %v (%q)", err, rec.Body.String())
+	}
+	if apiErr.Code != codeServiceUnavailable {
+		t.Errorf("code = %q, want %q", apiErr.Code, codeServiceUnavailable)
+	}
+	<-finished
+	if strings.Contains(rec.Body.String(), "tarde") {
+		t.Errorf("lo escrito tras el plazo llegó al cliente: %q", rec.Body.String())
+	}
+}
+
+// Un handler que no mira el plazo sigue en marcha tras el 503, pero no
+// puede escribir en el ResponseWriter real; sus consultas sí se cancelan.
+func TestRequestTimeoutHandlerIgnoresDeadline(t *testing.T) {
+	type late struct {
+		dbErr    error
+		writeErr error
+	}
+	result := make(chan late, 1)
+	stubborn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := dbContext(r)
+		defer cancel()
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("X-Tarde", "1")
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("tarde"))
+		result <- late{dbErr: ctx.Err(), writeErr: err}
+	})
+	h := withRequestTimeout(10 * time.Millisecond)(stubborn)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/v1/solicitudes", nil))
+	wantStatus(t, rec, http.StatusServiceUnavailable)
+	body := rec.Body.String()
+
+	got := <-result
+	if !errors.Is(got.dbErr, context.DeadlineExceeded) {
+		t.Errorf("contexto de dbContext tras el plazo: %v, want DeadlineExceeded", got.dbErr)
+	}
+	if !errors.Is(got.writeErr, http.ErrHandlerTimeout) {
+		t.Errorf("Write tras el plazo = %v, want ErrHandlerTimeout", got.writeErr)
+	}
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != body || rec.Header().Get("X-Tarde") != "" {
+		t.Errorf("la respuesta cambió tras el plazo: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestTimeoutFastHandler(t *testing.T) {
+	fast := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); !ok {
+			t.Error("el handler no recibe el plazo en el contexto")
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Prueba", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hola"))
+	})
+	h := withRequestTimeout(time.Second)(fast)
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set(requestIDHeader, "abc")
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/submit-service", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", rec.Code)
+	}
+	if got := rec.Body.String(); got != "hola" {
+		t.Errorf("body = %q, want %q", got, "hola")
+	}
+	for name, want := range map[string]string{"Content-Type": "text/plain", "X-Prueba": "1", requestIDHeader: "abc"} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRequestTimeoutSkipsStreamingRoutes(t *testing.T) {
+	for _, path := range []string{"/v1/solicitudes.csv", "/solicitudes.csv"} {
+		var streamed bool
+		h := withRequestTimeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				t.Errorf("%s: la exportación no debe tener plazo", path)
+			}
+			// Sin buffer, lo escrito llega al writer original al momento
+			w.Write([]byte("id\n"))
+			streamed = w.(*httptest.ResponseRecorder).Body.Len() > 0
+			time.Sleep(30 * time.Millisecond)
+			w.Write([]byte("1\n"))
+		}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+
+		if rec.Code != http.StatusOK || rec.Body.String() != "id\n1\n" {
+			t.Errorf("%s: status = %d, body = %q", path, rec.Code, rec.Body.String())
+		}
+		if !streamed {
+			t.Errorf("%s: la respuesta se guardó en memoria", path)
+		}
+	}
+}
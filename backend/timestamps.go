@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Todas las fechas se guardan y se devuelven en UTC. En JSON y en el CSV
+// van en RFC 3339, p. ej. "2026-01-31T09:15:00Z".
+
+// formatTimestamp da el formato con el que la API devuelve las fechas.
+func formatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// timestampLayouts son los formatos de texto en que los drivers pueden
+// devolver una fecha: MySQL sin parseTime y SQLite cuando la columna no se
+// reconoce como fecha.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	"2006-01-02 15:04:05Z07:00",
+}
+
+// parseTimestamp interpreta una fecha devuelta como texto por la base de
+// datos. Las fechas sin zona se consideran UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("fecha no reconocida: %q", s)
+}
+
+// dbTimestamp es un sql.Scanner para columnas TIMESTAMP que acepta tanto
+// time.Time como texto, según lo que devuelva el driver. Valid es false si
+// la columna es NULL.
+type dbTimestamp struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *dbTimestamp) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = dbTimestamp{}
+		return nil
+	case time.Time:
+		*t = dbTimestamp{Time: v.UTC(), Valid: true}
+		return nil
+	case []byte:
+		return t.Scan(string(v))
+	case string:
+		parsed, err := parseTimestamp(v)
+		if err != nil {
+			return err
+		}
+		*t = dbTimestamp{Time: parsed, Valid: true}
+		return nil
+	}
+	return fmt.Errorf("no se puede leer %T como fecha", src)
+}
+
+// String devuelve la fecha con formatTimestamp, o "" si es NULL.
+func (t dbTimestamp) String() string {
+	if !t.Valid {
+		return ""
+	}
+	return formatTimestamp(t.Time)
+}
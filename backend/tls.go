@@ -0,0 +1,19 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// tlsMinVersion traduce TLS_MIN_VERSION ("1.2" o "1.3") a la constante de
+// crypto/tls. Por defecto se exige TLS 1.2.
+func tlsMinVersion(raw string) (uint16, error) {
+	switch raw {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("TLS_MIN_VERSION debe ser \"1.2\" o \"1.3\": %q", raw)
+	}
+}
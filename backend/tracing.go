@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Nombre del servicio en las trazas si no se indica OTEL_SERVICE_NAME
+const defaultTraceServiceName = "rayner_tec"
+
+// tracer crea los spans de la aplicación. Mientras no se configure un
+// proveedor (sin OTEL_EXPORTER_OTLP_ENDPOINT) los spans no hacen nada.
+var tracer = otel.Tracer("pagemarmot")
+
+// initTracing envía las trazas por OTLP/HTTP al endpoint de
+// OTEL_EXPORTER_OTLP_ENDPOINT. El exportador lee también el resto de
+// variables OTEL_EXPORTER_OTLP_* (cabeceras, timeout...). Sin endpoint no
+// configura nada. Devuelve la función que envía los spans pendientes al
+// apagar.
+func initTracing(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if cfg.OTLPEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// OTEL_SERVICE_NAME y OTEL_RESOURCE_ATTRIBUTES tienen prioridad sobre el
+	// nombre por defecto
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(defaultTraceServiceName), semconv.ServiceVersion(version)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// tracingMiddleware abre un span por petición, como hijo de la traza que
+// llegue en traceparent si el cliente la envía. El nombre usa el patrón de
+// chi, igual que las métricas, para agrupar las peticiones por ruta.
+func tracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if route := routePattern(r); route != "" {
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// dbSystemAttribute identifica la base de datos en los spans de las
+// consultas.
+func dbSystemAttribute(driver string) attribute.KeyValue {
+	switch driver {
+	case "postgres":
+		return semconv.DBSystemNamePostgreSQL
+	case "sqlite3":
+		return semconv.DBSystemNameKey.String("sqlite")
+	default:
+		return semconv.DBSystemNameMySQL
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxFieldLength coincide con el VARCHAR(255) de la tabla, para no depender
+// de que MySQL trunque los valores en silencio.
+const maxFieldLength = 255
+
+// Tamaño máximo por defecto del cuerpo JSON (1 MB). Se configura con
+// MAX_BODY_BYTES.
+const defaultMaxBodyBytes = 1 << 20
+
+var maxBodyBytes int64 = defaultMaxBodyBytes
+
+// Franjas que admite el campo opcional horario_preferido
+var horariosPreferidos = []string{"mañana", "tarde", "noche"}
+
+// Longitud máxima por defecto del campo mensaje (MENSAJE_MAX_LENGTH).
+const defaultMaxMensajeLength = 2000
+
+var maxMensajeLength = defaultMaxMensajeLength
+
+// stripHTML quita las etiquetas HTML de los campos antes de guardarlos
+// (STRIP_HTML, activado por defecto), porque se muestran en el panel de
+// administración.
+var stripHTML = true
+
+// htmlTagRegexp reconoce etiquetas y comentarios HTML. Exige una letra, / o
+// ! tras el <, para no tocar textos como "3 < 5".
+var htmlTagRegexp = regexp.MustCompile(`<[a-zA-Z/!][^>]*>`)
+
+// Campo trampa por defecto para los bots (HONEYPOT_FIELD): el formulario lo
+// oculta, así que solo lo rellena quien no es una persona.
+const defaultHoneypotField = "website"
+
+var honeypotField = defaultHoneypotField
+
+// Patrón por defecto para el teléfono: solo dígitos, espacios, +, -, y
+// paréntesis, con al menos 7 dígitos. Se puede sobrescribir con la
+// variable de entorno TELEFONO_REGEX.
+const defaultTelefonoPattern = `^[\s()+-]*(?:\d[\s()+-]*){7,}$`
+
+var telefonoRegexp = regexp.MustCompile(defaultTelefonoPattern)
+
+// Longitud permitida del servicio, en caracteres
+const (
+	minServicioLength = 2
+	maxServicioLength = 100
+)
+
+// Patrón por defecto para el servicio: letras (con acentos), números,
+// espacios y puntuación básica. Importa sobre todo con ALLOWED_SERVICES=*,
+// cuando el servicio es texto libre. Se puede sobrescribir con
+// SERVICIO_REGEX.
+const defaultServicioPattern = `^[\p{L}\p{M}\p{N} .,;:'"()&/+-]+$`
+
+var servicioRegexp = regexp.MustCompile(defaultServicioPattern)
+
+// validationError indica qué campo de la solicitud no es válido y por qué.
+type validationError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *validationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// validationErrors reúne todos los campos inválidos de una solicitud, para
+// que el formulario pueda marcarlos todos a la vez.
+type validationErrors []*validationError
+
+func (e validationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// sanitizeSolicitud quita los caracteres de control de todos los campos
+// (salvo los saltos de línea y tabuladores del mensaje) y, con stripHTML,
+// las etiquetas HTML.
+func sanitizeSolicitud(s Solicitud) Solicitud {
+	s.Nombre = sanitizeField(s.Nombre, false)
+	s.Telefono = sanitizeField(s.Telefono, false)
+	s.Servicio = sanitizeField(s.Servicio, false)
+	s.Email = sanitizeField(s.Email, false)
+	s.Mensaje = sanitizeField(s.Mensaje, true)
+	s.HorarioPreferido = sanitizeField(s.HorarioPreferido, false)
+	return s
+}
+
+func sanitizeField(value string, multiline bool) string {
+	value = strings.Map(func(r rune) rune {
+		if multiline && (r == '\n' || r == '\t') {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, value)
+	if stripHTML {
+		value = htmlTagRegexp.ReplaceAllString(value, "")
+	}
+	return value
+}
+
+// trimSolicitud elimina los espacios al inicio y al final de cada campo.
+func trimSolicitud(s Solicitud) Solicitud {
+	s.Nombre = strings.TrimSpace(s.Nombre)
+	s.Telefono = strings.TrimSpace(s.Telefono)
+	s.Servicio = strings.TrimSpace(s.Servicio)
+	s.Email = strings.TrimSpace(s.Email)
+	s.Mensaje = strings.TrimSpace(s.Mensaje)
+	s.HorarioPreferido = strings.TrimSpace(s.HorarioPreferido)
+	return s
+}
+
+// validateSolicitud aplica las reglas de validación a una solicitud ya
+// recortada. Devuelve validationErrors con todos los campos inválidos, en el
+// orden del formulario.
+func validateSolicitud(s Solicitud) error {
+	var errs validationErrors
+	if err := validateRequired("nombre", s.Nombre); err != nil {
+		errs = append(errs, err)
+	}
+	if !telefonoRegexp.MatchString(s.Telefono) || utf8.RuneCountInString(s.Telefono) > maxFieldLength {
+		errs = append(errs, &validationError{Field: "telefono", Message: "Teléfono inválido"})
+	}
+	if err := validateRequired("servicio", s.Servicio); err != nil {
+		errs = append(errs, err)
+	} else if err := validateServicio(s.Servicio); err != nil {
+		errs = append(errs, err)
+	} else if _, ok := findServicio(s.Servicio); !ok {
+		errs = append(errs, &validationError{Field: "servicio", Message: "Servicio no reconocido"})
+	}
+	if s.Email != "" && !validEmail(s.Email) {
+		errs = append(errs, &validationError{Field: "email", Message: "Correo electrónico inválido"})
+	}
+	if utf8.RuneCountInString(s.Mensaje) > maxMensajeLength {
+		errs = append(errs, &validationError{Field: "mensaje", Message: fmt.Sprintf("El campo 'mensaje' no puede superar %d caracteres", maxMensajeLength)})
+	}
+	if s.HorarioPreferido != "" && !validHorario(s.HorarioPreferido) {
+		errs = append(errs, &validationError{Field: "horario_preferido", Message: "El campo 'horario_preferido' debe ser mañana, tarde o noche"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validHorario indica si horario es una de las franjas de
+// horariosPreferidos, sin distinguir mayúsculas.
+func validHorario(horario string) bool {
+	for _, h := range horariosPreferidos {
+		if strings.EqualFold(h, horario) {
+			return true
+		}
+	}
+	return false
+}
+
+// validEmail hace una comprobación básica del formato del correo: una sola
+// dirección, sin nombre ("Ana <ana@example.com>" no vale) y con dominio.
+func validEmail(email string) bool {
+	if utf8.RuneCountInString(email) > maxFieldLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return strings.Contains(domain, ".")
+}
+
+// validateRequired comprueba que un campo de texto no esté vacío y que no
+// supere maxFieldLength caracteres.
+func validateRequired(field, value string) *validationError {
+	if value == "" {
+		return &validationError{Field: field, Message: fmt.Sprintf("El campo '%s' es obligatorio", field)}
+	}
+	if utf8.RuneCountInString(value) > maxFieldLength {
+		return &validationError{Field: field, Message: fmt.Sprintf("El campo '%s' no puede superar %d caracteres", field, maxFieldLength)}
+	}
+	return nil
+}
+
+// validateServicio comprueba la longitud y los caracteres de un servicio
+// no vacío, antes de buscarlo en la lista de permitidos.
+func validateServicio(servicio string) *validationError {
+	if n := utf8.RuneCountInString(servicio); n < minServicioLength || n > maxServicioLength {
+		return &validationError{Field: "servicio", Message: fmt.Sprintf("El campo 'servicio' debe tener entre %d y %d caracteres", minServicioLength, maxServicioLength)}
+	}
+	if !servicioRegexp.MatchString(servicio) {
+		return &validationError{Field: "servicio", Message: "El campo 'servicio' contiene caracteres no permitidos"}
+	}
+	return nil
+}
+
+// errTrailingData indica que después del objeto JSON hay más datos.
+var errTrailingData = errors.New("datos después del objeto JSON")
+
+// decodeErrorResponse traduce un error de json.Decoder a un mensaje que
+// explique al cliente qué está mal en el cuerpo.
+func decodeErrorResponse(err error) APIError {
+	apiErr := APIError{Code: codeInvalidJSON, Message: "Error al decodificar la solicitud JSON"}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		apiErr.Message = "El cuerpo de la solicitud está vacío"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		apiErr.Message = "El JSON está incompleto"
+	case errors.Is(err, errTrailingData):
+		apiErr.Message = "El cuerpo solo puede contener un objeto JSON"
+	case errors.As(err, &syntaxErr):
+		apiErr.Message = fmt.Sprintf("JSON mal formado en la posición %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			apiErr.Field = typeErr.Field
+			apiErr.Message = fmt.Sprintf("El campo '%s' debe ser texto", typeErr.Field)
+		} else {
+			apiErr.Message = "El cuerpo debe ser un objeto JSON"
+		}
+	default:
+		// json no exporta un tipo para los campos desconocidos
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			field = strings.Trim(field, `"`)
+			apiErr.Field = field
+			apiErr.Message = fmt.Sprintf("Campo desconocido: '%s'", field)
+		}
+	}
+	return apiErr
+}
+
+// decodeSolicitud lee el cuerpo JSON de la petición, lo recorta y lo valida.
+// Si algo falla escribe la respuesta de error y devuelve false; en ese caso
+// el handler no debe tocar la base de datos.
+func decodeSolicitud(w http.ResponseWriter, r *http.Request) (Solicitud, bool) {
+	solicitud, _, ok := decodeSolicitudBody(w, r, false)
+	return solicitud, ok
+}
+
+// formExtras son los campos que el formulario público envía además de los
+// de la solicitud.
+type formExtras struct {
+	// Spam indica que el campo trampa (honeypotField) venía relleno
+	Spam         bool
+	CaptchaToken string
+}
+
+// Campo del cuerpo de /submit-service con el token de reCAPTCHA
+const captchaTokenField = "captcha_token"
+
+// decodeSubmission es decodeSolicitud para el formulario público: admite
+// además el campo trampa y el token del captcha. Si el campo trampa viene
+// relleno devuelve extras.Spam sin validar nada más ni responder.
+func decodeSubmission(w http.ResponseWriter, r *http.Request) (Solicitud, formExtras, bool) {
+	return decodeSolicitudBody(w, r, true)
+}
+
+func decodeSolicitudBody(w http.ResponseWriter, r *http.Request, public bool) (solicitud Solicitud, extras formExtras, ok bool) {
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return solicitud, extras, false
+	}
+	if public {
+		if body, extras = extractFormExtras(body); extras.Spam {
+			return solicitud, extras, false
+		}
+	}
+
+	if err := decodeStrict(body, &solicitud); err != nil {
+		writeAPIError(w, http.StatusBadRequest, decodeErrorResponse(err))
+		return solicitud, extras, false
+	}
+
+	solicitud, err := prepareSolicitud(requestLogger(r), solicitud)
+	if err != nil {
+		var vErrs validationErrors
+		if !errors.As(err, &vErrs) {
+			vErrs = validationErrors{{Message: err.Error()}}
+		}
+		// field se mantiene con el primer campo para los clientes que aún
+		// no leen errors
+		writeAPIError(w, http.StatusBadRequest, APIError{
+			Message: "Validación fallida",
+			Code:    codeValidation,
+			Field:   vErrs[0].Field,
+			Errors:  vErrs,
+		})
+		return solicitud, extras, false
+	}
+	return solicitud, extras, true
+}
+
+// prepareSolicitud limpia, recorta y valida una solicitud ya decodificada y la deja
+// como se guarda en la base de datos.
+func prepareSolicitud(log *slog.Logger, s Solicitud) (Solicitud, error) {
+	s = trimSolicitud(sanitizeSolicitud(s))
+	if err := validateSolicitud(s); err != nil {
+		return s, err
+	}
+
+	// Guardar el servicio con el nombre configurado, no como lo escribió el cliente
+	s.Servicio, _ = findServicio(s.Servicio)
+
+	s.HorarioPreferido = strings.ToLower(s.HorarioPreferido)
+
+	// Guardar el teléfono en E.164 para que la deduplicación y las llamadas
+	// funcionen igual venga como venga escrito
+	if normalized, err := normalizePhone(s.Telefono, defaultCountryCode); err == nil {
+		s.Telefono = normalized
+	} else {
+		log.Warn("No se pudo normalizar el teléfono, se guarda tal cual", "error", err)
+	}
+	return s, nil
+}
+
+// readJSONBody comprueba que el cuerpo sea application/json y lo lee
+// entero, hasta maxBodyBytes. Si algo falla escribe la respuesta de error y
+// devuelve false.
+func readJSONBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "El cuerpo debe enviarse como application/json")
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, codePayloadTooLarge, fmt.Sprintf("El cuerpo no puede superar %d bytes", maxBodyBytes))
+			return nil, false
+		}
+		writeAPIError(w, http.StatusBadRequest, decodeErrorResponse(err))
+		return nil, false
+	}
+	return body, true
+}
+
+// decodeStrict decodifica un único valor JSON en v, sin admitir campos
+// desconocidos ni datos detrás. Los errores se traducen con
+// decodeErrorResponse.
+func decodeStrict(body []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// Solo se admite un valor: cualquier cosa detrás es un error. Se lee
+	// como RawMessage para que DisallowUnknownFields no se queje de los
+	// campos del segundo objeto
+	if err := dec.Decode(&json.RawMessage{}); err != io.EOF {
+		if err == nil {
+			err = errTrailingData
+		}
+		return err
+	}
+	return nil
+}
+
+// extractFormExtras saca del objeto JSON el campo trampa y el token del
+// captcha para que la decodificación estricta no los rechace. Si el cuerpo
+// no es un objeto JSON lo deja igual y los errores los informa la
+// decodificación normal.
+func extractFormExtras(body []byte) ([]byte, formExtras) {
+	var extras formExtras
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return body, extras
+	}
+
+	value, hasHoneypot := fields[honeypotField]
+	if hasHoneypot {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil || strings.TrimSpace(s) != "" {
+			extras.Spam = true
+			return body, extras
+		}
+		delete(fields, honeypotField)
+	}
+	value, hasToken := fields[captchaTokenField]
+	if hasToken {
+		// Un token que no es texto cuenta como ausente
+		json.Unmarshal(value, &extras.CaptchaToken)
+		delete(fields, captchaTokenField)
+	}
+	if !hasHoneypot && !hasToken {
+		return body, extras
+	}
+
+	stripped, err := json.Marshal(fields)
+	if err != nil {
+		return body, extras
+	}
+	return stripped, extras
+}
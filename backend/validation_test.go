@@ -0,0 +1,439 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSubmitRequiresNombreAndServicio(t *testing.T) {
+	long := strings.Repeat("a", maxFieldLength+1)
+	for _, tc := range []struct {
+		name, nombre, servicio string
+		field, message         string
+	}{
+		{"nombre vacío", "", "Mantenimiento de PC", "nombre", "El campo 'nombre' es obligatorio"},
+		{"nombre con espacios", "   ", "Mantenimiento de PC", "nombre", "El campo 'nombre' es obligatorio"},
+		{"nombre largo", long, "Mantenimiento de PC", "nombre", "El campo 'nombre' no puede superar 255 caracteres"},
+		{"servicio vacío", "Ana", "", "servicio", "El campo 'servicio' es obligatorio"},
+		{"servicio con espacios", "Ana", " \t ", "servicio", "El campo 'servicio' es obligatorio"},
+		{"servicio largo", "Ana", long, "servicio", "El campo 'servicio' no puede superar 255 caracteres"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openTestDB(t)
+			rec := submit(t, solicitudBody(tc.nombre, "8095551111", tc.servicio))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+			}
+			var apiErr APIError
+			if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+				t.Fatal(err)
+			}
+			if apiErr.Code != codeValidation || len(apiErr.Errors) != 1 {
+				t.Fatalf("error = %+v", apiErr)
+			}
+			if got := apiErr.Errors[0]; got.Field != tc.field || got.Message != tc.message {
+				t.Errorf("error = %s: %s, want %s: %s", got.Field, got.Message, tc.field, tc.message)
+			}
+			if n := countSolicitudes(t); n != 0 {
+				t.Errorf("se guardaron %d solicitudes", n)
+			}
+		})
+	}
+}
+
+func TestValidateSolicitudListsEveryField(t *testing.T) {
+	err := validateSolicitud(Solicitud{Telefono: "12", Email: "no-es-correo"})
+	errs, ok := err.(validationErrors)
+	if !ok {
+		t.Fatalf("validateSolicitud = %v, want validationErrors", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "nombre,telefono,servicio,email" {
+		t.Errorf("campos = %s, want nombre,telefono,servicio,email", got)
+	}
+}
+
+func TestSubmitRejectsOversizedBody(t *testing.T) {
+	openTestDB(t)
+	prev := maxBodyBytes
+	maxBodyBytes = 64
+	t.Cleanup(func() { maxBodyBytes = prev })
+
+	rec := submit(t, solicitudBody("Ana", "8095551111", "Mantenimiento de PC")[:30]+strings.Repeat(" ", 100)+`}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413 (body %s)", rec.Code, rec.Body)
+	}
+	var apiErr APIError
+	json.Unmarshal(rec.Body.Bytes(), &apiErr)
+	if apiErr.Code != codePayloadTooLarge || apiErr.Message != "El cuerpo no puede superar 64 bytes" {
+		t.Errorf("error = %+v", apiErr)
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("se guardaron %d solicitudes", n)
+	}
+}
+
+func TestSubmitRequiresJSONContentType(t *testing.T) {
+	openTestDB(t)
+	body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+	for contentType, want := range map[string]int{
+		"":                                  http.StatusUnsupportedMediaType,
+		"text/plain":                        http.StatusUnsupportedMediaType,
+		"application/x-www-form-urlencoded": http.StatusUnsupportedMediaType,
+		"application/json; charset=utf-8":   http.StatusCreated,
+	} {
+		req := httptest.NewRequest("POST", "/v1/submit-service", strings.NewReader(body))
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		rec := httptest.NewRecorder()
+		submitServiceHandler(rec, req)
+		if rec.Code != want {
+			t.Errorf("Content-Type %q: status = %d, want %d (body %s)", contentType, rec.Code, want, rec.Body)
+			continue
+		}
+		if want == http.StatusUnsupportedMediaType {
+			var apiErr APIError
+			json.Unmarshal(rec.Body.Bytes(), &apiErr)
+			if apiErr.Code != codeUnsupportedMediaType {
+				t.Errorf("Content-Type %q: code = %q", contentType, apiErr.Code)
+			}
+		}
+	}
+}
+
+func TestSubmitRejectsMalformedJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name, body     string
+		field, message string
+	}{
+		{"campo desconocido", `{"nombr": "Ana", "telefono": "8095551111", "servicio": "Mantenimiento de PC"}`, "nombr", "Campo desconocido: 'nombr'"},
+		{"datos detrás", solicitudBody("Ana", "8095551111", "Mantenimiento de PC") + `{"nombre": "Luis"}`, "", "El cuerpo solo puede contener un objeto JSON"},
+		{"basura detrás", solicitudBody("Ana", "8095551111", "Mantenimiento de PC") + ` xyz`, "", "JSON mal formado en la posición 80"},
+		{"vacío", ``, "", "El cuerpo de la solicitud está vacío"},
+		{"incompleto", `{"nombre": "Ana"`, "", "El JSON está incompleto"},
+		{"tipo incorrecto", `{"nombre": 5, "telefono": "8095551111", "servicio": "Mantenimiento de PC"}`, "nombre", "El campo 'nombre' debe ser texto"},
+		{"no es un objeto", `["Ana"]`, "", "El cuerpo debe ser un objeto JSON"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openTestDB(t)
+			rec := submit(t, tc.body)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+			}
+			var apiErr APIError
+			json.Unmarshal(rec.Body.Bytes(), &apiErr)
+			if apiErr.Code != codeInvalidJSON || apiErr.Field != tc.field || apiErr.Message != tc.message {
+				t.Errorf("error = %+v, want %s: %s", apiErr, tc.field, tc.message)
+			}
+			if n := countSolicitudes(t); n != 0 {
+				t.Errorf("se guardaron %d solicitudes", n)
+			}
+		})
+	}
+}
+
+func TestSubmitEmail(t *testing.T) {
+	for _, tc := range []struct {
+		name, email string
+		wantStatus  int
+		wantStored  sql.NullString
+	}{
+		{"válido", "ana@example.com", http.StatusCreated, sql.NullString{String: "ana@example.com", Valid: true}},
+		{"ausente", "", http.StatusCreated, sql.NullString{}},
+		{"sin dominio", "ana@", http.StatusBadRequest, sql.NullString{}},
+		{"sin punto en el dominio", "ana@localhost", http.StatusBadRequest, sql.NullString{}},
+		{"con nombre", "Ana <ana@example.com>", http.StatusBadRequest, sql.NullString{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openTestDB(t)
+			body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+			if tc.email != "" {
+				body = fmt.Sprintf(`{"nombre": "Ana", "telefono": "8095551111", "servicio": "Mantenimiento de PC", "email": %q}`, tc.email)
+			}
+			rec := submit(t, body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantStatus != http.StatusCreated {
+				var apiErr APIError
+				json.Unmarshal(rec.Body.Bytes(), &apiErr)
+				if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "email" {
+					t.Errorf("error = %+v, want el campo email", apiErr)
+				}
+				return
+			}
+
+			var resp struct {
+				Email string `json:"email"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if resp.Email != tc.email {
+				t.Errorf("email en la respuesta = %q, want %q", resp.Email, tc.email)
+			}
+			var stored sql.NullString
+			db.QueryRow(`SELECT email FROM solicitudes`).Scan(&stored)
+			if stored != tc.wantStored {
+				t.Errorf("email guardado = %+v, want %+v", stored, tc.wantStored)
+			}
+		})
+	}
+}
+
+func TestSubmitReturnsEveryInvalidField(t *testing.T) {
+	openTestDB(t)
+	rec := submit(t, `{"nombre": " ", "telefono": "12", "servicio": "Mantenimiento de PC", "email": "ana@"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+	}
+	var apiErr APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Message != "Validación fallida" || apiErr.Code != codeValidation {
+		t.Errorf("error = %s (%s), want Validación fallida (%s)", apiErr.Message, apiErr.Code, codeValidation)
+	}
+	// field sigue siendo el primero para los clientes antiguos
+	if apiErr.Field != "nombre" {
+		t.Errorf("field = %q, want nombre", apiErr.Field)
+	}
+	var fields []string
+	for _, e := range apiErr.Errors {
+		if e.Message == "" {
+			t.Errorf("el campo %s no trae mensaje", e.Field)
+		}
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "nombre,telefono,email" {
+		t.Errorf("campos = %s, want nombre,telefono,email", got)
+	}
+	if n := countSolicitudes(t); n != 0 {
+		t.Errorf("se guardaron %d solicitudes", n)
+	}
+}
+
+func TestBulkImportReportsEveryInvalidField(t *testing.T) {
+	openTestDB(t)
+	body := `[` + solicitudBody("Ana", "8095551111", "Mantenimiento de PC") + `, {"nombre": "", "telefono": "12", "servicio": ""}]`
+	req := httptest.NewRequest("POST", "/v1/solicitudes/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	bulkImportHandler(rec, req)
+
+	var result bulkImportResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("cuerpo no es JSON: %v (%s)", err, rec.Body)
+	}
+	if result.Inserted != 1 {
+		t.Errorf("inserted = %d, want 1", result.Inserted)
+	}
+	var fields []string
+	for _, e := range result.Errors {
+		if e.Index != 1 {
+			t.Errorf("error en el elemento %d, want 1", e.Index)
+		}
+		fields = append(fields, e.Field)
+	}
+	if got := strings.Join(fields, ","); got != "nombre,telefono,servicio" {
+		t.Errorf("campos = %s, want nombre,telefono,servicio", got)
+	}
+}
+
+func TestSanitizeSolicitud(t *testing.T) {
+	got := sanitizeSolicitud(Solicitud{
+		Nombre:   "José <script>alert('x')</script>Peña",
+		Telefono: "809\x00555\r\n1111",
+		Servicio: "<b>Mantenimiento</b> de PC",
+		Mensaje:  "Línea 1\nLínea\t2\x00<!-- nota -->\r\n3 < 4",
+	})
+	want := Solicitud{
+		Nombre:   "José alert('x')Peña",
+		Telefono: "8095551111",
+		Servicio: "Mantenimiento de PC",
+		Mensaje:  "Línea 1\nLínea\t2\n3 < 4",
+	}
+	if got != want {
+		t.Errorf("sanitizeSolicitud = %+v, want %+v", got, want)
+	}
+}
+
+func TestSanitizeKeepsHTMLWhenDisabled(t *testing.T) {
+	prev := stripHTML
+	stripHTML = false
+	t.Cleanup(func() { stripHTML = prev })
+
+	// Los caracteres de control se quitan igual
+	if got := sanitizeField("<b>Ana</b>\x00", false); got != "<b>Ana</b>" {
+		t.Errorf("sanitizeField = %q, want <b>Ana</b>", got)
+	}
+}
+
+func TestSubmitStoresSanitizedFields(t *testing.T) {
+	openTestDB(t)
+	rec := submit(t, `{"nombre": "<script>document.cookie</script>María\u0000 Núñez\n", "telefono": "8095551111", "servicio": "Mantenimiento de PC"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body)
+	}
+	var nombre string
+	if err := db.QueryRow(`SELECT nombre FROM solicitudes`).Scan(&nombre); err != nil {
+		t.Fatal(err)
+	}
+	if nombre != "document.cookieMaría Núñez" {
+		t.Errorf("nombre guardado = %q, want document.cookieMaría Núñez", nombre)
+	}
+
+	// Si al limpiar no queda nada, el campo cuenta como vacío
+	rec = submit(t, `{"nombre": "<script></script>\u0000", "telefono": "8095551111", "servicio": "Mantenimiento de PC"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("nombre solo con etiquetas: status = %d, want 400", rec.Code)
+	}
+}
+
+// withFreeFormServices acepta cualquier servicio mientras dura el test, como
+// ALLOWED_SERVICES=*.
+func withFreeFormServices(t *testing.T) {
+	t.Helper()
+	prev := allowedServices
+	allowedServices = nil
+	t.Cleanup(func() { allowedServices = prev })
+}
+
+func TestSubmitValidatesServicio(t *testing.T) {
+	const (
+		lengthMsg  = "El campo 'servicio' debe tener entre 2 y 100 caracteres"
+		charsetMsg = "El campo 'servicio' contiene caracteres no permitidos"
+	)
+	for _, tc := range []struct {
+		name, servicio string
+		message        string
+	}{
+		{"demasiado corto", "A", lengthMsg},
+		{"demasiado largo", strings.Repeat("a", 101), lengthMsg},
+		{"etiqueta a medias", "PC <b", charsetMsg},
+		{"emoji", "Reparación 💻", charsetMsg},
+		{"llaves", "Reparación {urgente}", charsetMsg},
+		{"mínimo", "TV", ""},
+		{"máximo con acentos", strings.Repeat("ñ", 100), ""},
+		{"puntuación básica", "Redes (Wi-Fi), cámaras & más: 2/3", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openTestDB(t)
+			withFreeFormServices(t)
+			rec := submit(t, solicitudBody("Ana", "8095551111", tc.servicio))
+			if tc.message == "" {
+				if rec.Code != http.StatusCreated {
+					t.Fatalf("status = %d, want 201 (body %s)", rec.Code, rec.Body)
+				}
+				return
+			}
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400 (body %s)", rec.Code, rec.Body)
+			}
+			var apiErr APIError
+			json.Unmarshal(rec.Body.Bytes(), &apiErr)
+			if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "servicio" || apiErr.Errors[0].Message != tc.message {
+				t.Errorf("errors = %+v, want servicio: %s", apiErr.Errors, tc.message)
+			}
+		})
+	}
+}
+
+func TestValidateServicioCustomPattern(t *testing.T) {
+	prev := servicioRegexp
+	servicioRegexp = regexp.MustCompile(`^[A-Z][a-z ]+$`)
+	t.Cleanup(func() { servicioRegexp = prev })
+
+	if err := validateServicio("Mantenimiento de pc"); err != nil {
+		t.Errorf("validateServicio = %v", err)
+	}
+	if err := validateServicio("mantenimiento"); err == nil {
+		t.Error("validateServicio aceptó un servicio fuera de SERVICIO_REGEX")
+	}
+}
+
+func TestLoadConfigServicio(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		vars      map[string]string
+		wantErr   string
+		wantAllow []string
+	}{
+		{"por defecto", nil, "", defaultAllowedServices},
+		{"cualquiera", map[string]string{"ALLOWED_SERVICES": "*"}, "", nil},
+		{"patrón no válido", map[string]string{"SERVICIO_REGEX": "["}, "SERVICIO_REGEX no es una expresión regular válida", nil},
+		{"servicio de la lista no válido", map[string]string{"ALLOWED_SERVICES": "Mantenimiento de PC,X"}, "ALLOWED_SERVICES contiene un servicio que no cumple SERVICIO_REGEX", nil},
+		{"servicio fuera del patrón", map[string]string{"ALLOWED_SERVICES": "Redes", "SERVICIO_REGEX": "^[0-9]+$"}, "ALLOWED_SERVICES contiene un servicio que no cumple SERVICIO_REGEX", nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestEnv(t, tc.vars)
+			cfg, err := LoadConfig()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("LoadConfig = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig = %v", err)
+			}
+			if strings.Join(cfg.AllowedServices, ",") != strings.Join(tc.wantAllow, ",") || (cfg.AllowedServices == nil) != (tc.wantAllow == nil) {
+				t.Errorf("AllowedServices = %q, want %q", cfg.AllowedServices, tc.wantAllow)
+			}
+		})
+	}
+}
+
+func TestSubmitHorarioPreferido(t *testing.T) {
+	for _, tc := range []struct {
+		name, horario string
+		wantStatus    int
+		wantStored    sql.NullString
+	}{
+		{"ausente", "", http.StatusCreated, sql.NullString{}},
+		{"mañana", "mañana", http.StatusCreated, sql.NullString{String: "mañana", Valid: true}},
+		{"mayúsculas y espacios", " TARDE ", http.StatusCreated, sql.NullString{String: "tarde", Valid: true}},
+		{"noche", "Noche", http.StatusCreated, sql.NullString{String: "noche", Valid: true}},
+		{"sin tilde", "manana", http.StatusBadRequest, sql.NullString{}},
+		{"otra franja", "madrugada", http.StatusBadRequest, sql.NullString{}},
+		{"hora", "18:00", http.StatusBadRequest, sql.NullString{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			openTestDB(t)
+			body := solicitudBody("Ana", "8095551111", "Mantenimiento de PC")
+			if tc.horario != "" {
+				body = fmt.Sprintf(`{"nombre": "Ana", "telefono": "8095551111", "servicio": "Mantenimiento de PC", "horario_preferido": %q}`, tc.horario)
+			}
+			rec := submit(t, body)
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.wantStatus, rec.Body)
+			}
+			if tc.wantStatus != http.StatusCreated {
+				var apiErr APIError
+				json.Unmarshal(rec.Body.Bytes(), &apiErr)
+				if len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "horario_preferido" ||
+					apiErr.Errors[0].Message != "El campo 'horario_preferido' debe ser mañana, tarde o noche" {
+					t.Errorf("error = %+v, want el campo horario_preferido", apiErr)
+				}
+				if n := countSolicitudes(t); n != 0 {
+					t.Errorf("se guardaron %d solicitudes", n)
+				}
+				return
+			}
+
+			var stored sql.NullString
+			db.QueryRow(`SELECT horario_preferido FROM solicitudes`).Scan(&stored)
+			if stored != tc.wantStored {
+				t.Errorf("horario guardado = %+v, want %+v", stored, tc.wantStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Valores por defecto de los reintentos del webhook (WEBHOOK_*). Cada
+// intento tiene su propio timeout de webhookTimeout.
+const (
+	defaultWebhookMaxAttempts = 3
+	defaultWebhookBaseDelay   = time.Second
+	defaultWebhookMaxElapsed  = 25 * time.Second
+	webhookTimeout            = 5 * time.Second
+	// Tope de WEBHOOK_MAX_ATTEMPTS; el envío entero tampoco puede pasar
+	// de notifyTimeout
+	maxWebhookAttempts = 10
+)
+
+// webhookRetryConfig configura los reintentos: hasta MaxAttempts intentos,
+// esperando BaseDelay, el doble, el cuádruple... con un margen aleatorio.
+// El envío entero, reintentos incluidos, se corta a los MaxElapsed.
+type webhookRetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxElapsed  time.Duration
+}
+
+// webhookRetry son los reintentos de todos los webhooks; Config.apply la
+// fija.
+var webhookRetry = webhookRetryConfig{
+	MaxAttempts: defaultWebhookMaxAttempts,
+	BaseDelay:   defaultWebhookBaseDelay,
+	MaxElapsed:  defaultWebhookMaxElapsed,
+}
+
+// webhookNotifier envía cada solicitud nueva como JSON a WEBHOOK_URL
+// (Slack, Zapier, ...).
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+	retry  webhookRetryConfig
+}
+
+func newWebhookNotifier(url string) *webhookNotifier {
+	return &webhookNotifier{url: url, client: outboundClient, retry: webhookRetry}
+}
+
+// validHTTPURL indica si u es una URL http o https con host.
+func validHTTPURL(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+func (n *webhookNotifier) Name() string { return "webhook" }
+
+// Notify hace POST de la solicitud, con su id y fecha de creación. Se
+// reintenta ante errores de red, timeouts y respuestas 5xx o 429; el resto
+// de 4xx son definitivas.
+func (n *webhookNotifier) Notify(ctx context.Context, s SolicitudGuardada) error {
+	body, err := json.Marshal(newSolicitudResponse(s.public()))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.retry.MaxElapsed)
+	defer cancel()
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil {
+			// Al primer intento basta con el log de notifyNuevaSolicitud
+			level := slog.LevelDebug
+			if attempt > 1 {
+				level = slog.LevelInfo
+			}
+			logger.Log(ctx, level, "Webhook entregado", "id", s.ID, "attempts", attempt)
+			return nil
+		}
+		if !retry {
+			return fmt.Errorf("error definitivo en el intento %d: %w", attempt, err)
+		}
+		if attempt == n.retry.MaxAttempts {
+			return fmt.Errorf("intento %d de %d: %w", attempt, n.retry.MaxAttempts, err)
+		}
+		delay := n.retry.backoff(attempt)
+		if time.Since(start)+delay >= n.retry.MaxElapsed {
+			return fmt.Errorf("tiempo máximo de reintentos agotado tras %d intentos: %w", attempt, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("cancelado tras %d intentos: %w", attempt, ctx.Err())
+		}
+	}
+}
+
+// backoff es la espera tras el intento attempt: BaseDelay·2^(attempt-1),
+// de la que se toma al azar entre la mitad y el total para que los
+// reintentos de varias solicitudes no lleguen a la vez.
+func (c webhookRetryConfig) backoff(attempt int) time.Duration {
+	delay := c.BaseDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// post hace un intento de envío e indica si tiene sentido reintentar.
+func (n *webhookNotifier) post(ctx context.Context, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// Errores de red y timeouts se reintentan; la cancelación no
+		return !errors.Is(err, context.Canceled), err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+	return retry, fmt.Errorf("el webhook respondió %s", resp.Status)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Valores por defecto del modo de escritura en lotes (WRITE_BUFFER_*)
+const (
+	defaultWriteBufferSize          = 1000
+	defaultWriteBufferBatchSize     = 100
+	defaultWriteBufferFlushInterval = 200 * time.Millisecond
+)
+
+// writeBufferConfig configura el modo de escritura en lotes: las
+// solicitudes del formulario se encolan y se guardan en segundo plano con
+// un INSERT de varias filas.
+type writeBufferConfig struct {
+	Enabled bool
+	// Size es la capacidad de la cola; llena, se responde 503
+	Size int
+	// Se guarda un lote al llegar a BatchSize solicitudes o cada
+	// FlushInterval, lo que ocurra antes
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// pendingSolicitud es una solicitud validada que aún no se ha guardado,
+// con los datos del cliente que la envió.
+type pendingSolicitud struct {
+	Solicitud
+	Client clientInfo
+}
+
+// writeBuffer encola las solicitudes y las guarda en lotes desde una única
+// goroutine.
+type writeBuffer struct {
+	queue         chan pendingSolicitud
+	batchSize     int
+	flushInterval time.Duration
+	done          chan struct{}
+
+	// mu evita encolar en la cola ya cerrada si el apagado vence con
+	// peticiones en curso
+	mu     sync.RWMutex
+	closed bool
+}
+
+// solicitudBuffer es la cola del modo de escritura en lotes, o nil si
+// WRITE_BUFFER_ENABLED no está activada.
+var solicitudBuffer *writeBuffer
+
+func newWriteBuffer(cfg writeBufferConfig) *writeBuffer {
+	return &writeBuffer{
+		queue:         make(chan pendingSolicitud, cfg.Size),
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		done:          make(chan struct{}),
+	}
+}
+
+// Enqueue añade la solicitud a la cola sin esperar. Devuelve false si la
+// cola está llena o cerrada.
+func (b *writeBuffer) Enqueue(p pendingSolicitud) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return false
+	}
+	select {
+	case b.queue <- p:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len devuelve las solicitudes que esperan en la cola.
+func (b *writeBuffer) Len() int {
+	return len(b.queue)
+}
+
+// run guarda las solicitudes de la cola hasta que se cierra, y entonces
+// guarda lo que quede.
+func (b *writeBuffer) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]pendingSolicitud, 0, b.batchSize)
+	for {
+		select {
+		case p, ok := <-b.queue:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+			batch = append(batch, p)
+			if len(batch) >= b.batchSize {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			b.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// Close deja de aceptar solicitudes y espera a que se guarden las que
+// quedan en la cola, o a que venza ctx.
+func (b *writeBuffer) Close(ctx context.Context) {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		logger.Warn("Apagado con solicitudes sin guardar en la cola", "pending", b.Len())
+	}
+}
+
+// flush guarda un lote en una transacción y lanza las notificaciones de las
+// solicitudes guardadas.
+func (b *writeBuffer) flush(batch []pendingSolicitud) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dbQueryTimeout)
+	defer cancel()
+
+	var saved []SolicitudGuardada
+	err := withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		saved, err = insertSolicitudesReturning(ctx, tx, batch)
+		return err
+	})
+	if err != nil && fallbackSpool != nil && isDBUnavailableError(err) {
+		spoolErr := fallbackSpool.Append(batch)
+		if spoolErr == nil {
+			logger.Warn("Base de datos no accesible, lote guardado en el respaldo", "count", len(batch), "error", err)
+			return
+		}
+		logger.Error("Error al escribir en el fichero de respaldo", "error", spoolErr)
+	}
+	if err != nil {
+		// Al cliente ya se le respondió 202. Con FALLBACK_FILE el lote se
+		// aparta para recuperarlo a mano; el log no lleva datos personales
+		logger.Error("Error al guardar un lote de solicitudes", "count", len(batch), "error", err)
+		for i, p := range batch {
+			logger.Error("Solicitud no guardada", "entry", i+1, "servicio", p.Servicio)
+		}
+		if fallbackSpool != nil {
+			fallbackSpool.setAside(batch)
+		}
+		return
+	}
+
+	logger.Info("Lote de solicitudes guardado", "count", len(saved))
+	responseCache.invalidate()
+	for _, s := range saved {
+		recordSolicitudCreada(s.Servicio)
+		notifyNuevaSolicitud(logger, s)
+	}
+}
+
+// insertSolicitudesReturning guarda el lote con un único INSERT y lo
+// relee para obtener el id y la fecha de cada solicitud. Como no todos los
+// drivers devuelven los ids de un INSERT de varias filas, se releen las
+// filas posteriores al id máximo anterior y se emparejan por contenido.
+func insertSolicitudesReturning(ctx context.Context, tx *sql.Tx, batch []pendingSolicitud) ([]SolicitudGuardada, error) {
+	var maxID int64
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM solicitudes`).Scan(&maxID); err != nil {
+		return nil, err
+	}
+	if err := insertSolicitudes(ctx, tx, batch); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, rebind(`SELECT `+solicitudColumns+` FROM solicitudes WHERE id > ? ORDER BY id`), maxID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Otra petición pudo insertar a la vez: solo cuentan las filas que
+	// coinciden con alguna del lote
+	pending := make(map[pendingSolicitud]int, len(batch))
+	for _, p := range batch {
+		pending[p]++
+	}
+	saved := make([]SolicitudGuardada, 0, len(batch))
+	for len(saved) < len(batch) && rows.Next() {
+		s, err := scanSolicitud(rows)
+		if err != nil {
+			return nil, err
+		}
+		key := pendingSolicitud{Solicitud: s.Solicitud, Client: clientInfo{IP: s.IPAddress, UserAgent: s.UserAgent}}
+		if pending[key] > 0 {
+			pending[key]--
+			saved = append(saved, s)
+		}
+	}
+	return saved, rows.Err()
+}
+
+// acceptBuffered encola la solicitud y responde 202 sin esperar a la base
+// de datos. Con la cola llena responde 503 para que el cliente lo reintente
+// en lugar de acumular peticiones en espera.
+func acceptBuffered(w http.ResponseWriter, r *http.Request, s Solicitud) {
+	if !solicitudBuffer.Enqueue(pendingSolicitud{Solicitud: s, Client: requestClientInfo(r)}) {
+		requestLogger(r).Warn("Cola de escritura llena, se rechaza la solicitud", "capacity", cap(solicitudBuffer.queue), "status", http.StatusServiceUnavailable)
+		w.Header().Set("Retry-After", "1")
+		writeError(w, http.StatusServiceUnavailable, codeServiceUnavailable, "Hay demasiadas solicitudes en este momento, inténtalo de nuevo en unos segundos")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": translate(responseLang(w), "Solicitud recibida")})
+}
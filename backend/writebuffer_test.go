@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestWriteBufferFlushSavesBatch(t *testing.T) {
+	openTestDB(t)
+	b := newWriteBuffer(writeBufferConfig{Size: 10, BatchSize: 10})
+
+	b.flush([]pendingSolicitud{
+		{Solicitud: Solicitud{Nombre: "Ana", Telefono: "+18095551111", Servicio: "Mantenimiento de PC"}, Client: clientInfo{IP: "203.0.113.7"}},
+		{Solicitud: Solicitud{Nombre: "Luis", Telefono: "+18095552222", Servicio: "Recuperación de Datos"}},
+	})
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM solicitudes`).Scan(&count); err != nil || count != 2 {
+		t.Fatalf("count = %d, err = %v; want 2", count, err)
+	}
+}
+
+func TestWriteBufferFailedBatchLogsNoPersonalData(t *testing.T) {
+	conn := openTestDB(t)
+	s := withFallbackSpool(t)
+	if _, err := conn.Exec(`DROP TABLE solicitudes`); err != nil {
+		t.Fatal(err)
+	}
+	var logs bytes.Buffer
+	prev := logger
+	logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	t.Cleanup(func() { logger = prev })
+
+	b := newWriteBuffer(writeBufferConfig{Size: 10, BatchSize: 10})
+	b.flush([]pendingSolicitud{{
+		Solicitud: Solicitud{Nombre: "Ana Pérez", Telefono: "+18095551111", Servicio: "Mantenimiento de PC", Email: "ana@example.com", Mensaje: "Mi portátil no enciende"},
+		Client:    clientInfo{IP: "203.0.113.7"},
+	}})
+
+	for _, personal := range []string{"Ana Pérez", "8095551111", "ana@example.com", "portátil", "203.0.113.7"} {
+		if strings.Contains(logs.String(), personal) {
+			t.Errorf("el log contiene %q: %s", personal, logs.String())
+		}
+	}
+	if !strings.Contains(logs.String(), "Mantenimiento de PC") {
+		t.Errorf("el log no indica el servicio: %s", logs.String())
+	}
+	failed, err := readSpooled(s.failedPath())
+	if err != nil || len(failed) != 1 || failed[0].Nombre != "Ana Pérez" || failed[0].IP != "203.0.113.7" {
+		t.Errorf("apartadas = %+v, err = %v", failed, err)
+	}
+}